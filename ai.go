@@ -2,10 +2,9 @@ package homeworlds
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"log"
 	"math/rand"
-	"os"
 	"strings"
 	"time"
 )
@@ -29,7 +28,7 @@ func PositionFromGame(g *Game) Position {
 		pos.bank.Set(p, n)
 	}
 	stars := g.sortedStars()
-	pos.stars = make([]Dwarf, 2, len(g.Stars))
+	pos.stars = make([]Dwarf, 2, 2+len(g.Stars))
 	pos.stars[North] = dwarfFromStar(g.Homeworld(North))
 	pos.stars[South] = dwarfFromStar(g.Homeworld(South))
 	for _, name := range stars {
@@ -42,6 +41,47 @@ func PositionFromGame(g *Game) Position {
 	return pos
 }
 
+// GameFromPosition builds a game from a position.
+// The homeworlds are named after their owners
+// and the other stars are given new names.
+func GameFromPosition(pos Position) *Game {
+	g := &Game{
+		NumPlayers:    2,
+		CurrentPlayer: pos.CurrentPlayer(),
+		Bank:          make(map[Piece]int),
+		Homeworlds:    make(map[Player]string),
+		Stars:         make(map[string]*Star),
+	}
+	for p := R1; p <= B3; p++ {
+		g.Bank[p] = pos.bank.Get(p)
+	}
+	newName := g.newStarName()
+	for i, d := range pos.stars {
+		if d.pieces.IsEmpty() {
+			continue
+		}
+		s := &Star{
+			Pieces: d.pieces.pieces(),
+			Ships:  make(map[Player][]Piece),
+		}
+		if i < 2 {
+			s.Name = Player(i).String()
+			s.IsHomeworld = true
+			g.Homeworlds[Player(i)] = s.Name
+		} else {
+			s.Name = newName()
+		}
+		for pl := North; pl <= South; pl++ {
+			if ships := d.ships[pl].pieces(); ships != nil {
+				s.Ships[pl] = ships
+			}
+		}
+		g.Stars[s.Name] = s
+	}
+	g.ClearHistory()
+	return g
+}
+
 func (pos *Position) CurrentPlayer() Player {
 	return Player(pos.player)
 }
@@ -91,6 +131,7 @@ const (
 	Attack
 	Catastrope
 	Sacrifice
+	Homeworld
 )
 
 func (b BasicAction) Type() ActionType { return ActionType(b.typ) }
@@ -113,6 +154,7 @@ func (t ActionType) String() string {
 		Attack:     "Attack",
 		Catastrope: "Catastrope",
 		Sacrifice:  "Sacrifice",
+		Homeworld:  "Homeworld",
 	}[t]
 }
 
@@ -286,12 +328,19 @@ func (pos Position) SacrificeActions() []Action {
 type sacrificeGenerator struct {
 	acts []Action
 	//poses []Position
+
+	// colors, if non-zero, restricts the generator to
+	// sacrificing ships whose color bit (1<<color) is set
+	colors uint
 }
 
 func (sg *sacrificeGenerator) Generate(pos Position) []Action {
 	for id, s := range pos.stars {
 		ships := s.Ships(pos.CurrentPlayer())
 		for it := ships.Iter(); !it.Done(); it.Next() {
+			if sg.colors != 0 && sg.colors&(1<<it.Piece().Color()) == 0 {
+				continue
+			}
 			if it.Count() > 0 {
 				n := int(it.Piece().Size())
 				if it.Piece() == Y3 {
@@ -310,9 +359,8 @@ func (sg *sacrificeGenerator) Generate(pos Position) []Action {
 }
 
 func (sg *sacrificeGenerator) gen(sa Action, pos *Position, n int) {
-	//if !pos.sanityCheck() {
-	//	fmt.Printf("last action: %v\n", sa)
-	//	return actions
+	//if err := pos.Check(); err != nil {
+	//	return
 	//}
 	switch sa.Ship().Color() {
 	case Red:
@@ -408,6 +456,20 @@ func (a Action) append(b BasicAction) Action {
 	return a
 }
 
+// Do plays an action, including each step of a sacrifice,
+// and returns the position at the start of the next player's turn.
+// The action must be legal.
+func (pos Position) Do(a Action) Position {
+	pos = pos.do(a.Basic())
+	if a.Type() == Sacrifice {
+		for i := 0; i < a.N(); i++ {
+			pos = pos.do(a.Action(i))
+		}
+	}
+	pos.endturn()
+	return pos
+}
+
 func (pos Position) do(b BasicAction) Position {
 	switch b.Type() {
 	case Pass:
@@ -418,7 +480,6 @@ func (pos Position) do(b BasicAction) Position {
 		return pos.trade(b.Ship(), b.NewShip(), b.System())
 	case Move:
 		if b.System() >= len(pos.stars) {
-			log.Println(pos)
 			panic(fmt.Sprintf("no such system: %d", b.System()))
 		}
 		if b.ToSystem() >= len(pos.stars) {
@@ -571,7 +632,10 @@ func (pos Position) sacrifice(p Piece, s int) Position {
 	return pos
 }
 
-func (pos Position) sanityCheck() bool {
+// Check verifies that every piece is accounted for:
+// there must be three of each piece
+// between the bank, the stars and the ships.
+func (pos Position) Check() error {
 	b := make(map[Piece]int)
 	for i := 0; i < 12; i++ {
 		b[Piece(i)] = 3
@@ -588,18 +652,16 @@ func (pos Position) sanityCheck() bool {
 		}
 	}
 
-	ok := true
+	var errs []string
 	for i := 0; i < 12; i++ {
 		if pos.bank.Get(Piece(i)) != b[Piece(i)] {
-			ok = false
-			log.Printf("bank: have %d %s, expected %d", pos.bank.Get(Piece(i)), Piece(i), b[Piece(i)])
+			errs = append(errs, fmt.Sprintf("bank: have %d %s, expected %d", pos.bank.Get(Piece(i)), Piece(i), b[Piece(i)]))
 		}
 	}
-	if !ok {
-		fmt.Fprintf(os.Stderr, "%v\n", pos)
-		//panic("sanity check failed")
+	if errs != nil {
+		return errors.New(strings.Join(errs, "; "))
 	}
-	return ok
+	return nil
 }
 
 func (b Bank) String() string {
@@ -625,98 +687,304 @@ func (pos Position) String() string {
 }
 
 type AI struct {
-	r     *rand.Rand
-	depth int
-	trace int  // trace up to depth
-	debug bool // enable sanity checks
+	cfg    AIConfig
+	r      *rand.Rand
+	depth  int
+	qdepth int  // quiescence search limit
+	trace  int  // trace up to depth
+	debug  bool // enable sanity checks
+	eval   Evaluator
+	engine Engine
+	logger Logger
+
+	// repetition detection
+	repetition RepetitionPolicy
+	history    map[string]int
+
+	// time limit
+	deadline time.Time
+	timeout  bool
+	stop     <-chan struct{}
+
+	// depth of the last search which completed
+	reached int
+
+	// principal variation: pv[ply] is the best line found from ply,
+	// and line is the best line of the last search which completed
+	pv   [][]Action
+	line []Action
 
 	// stats
 	evaluated int64
 	visited   int64
 }
 
+// NewAI returns an AI with the default configuration.
 func NewAI() *AI {
+	ai, err := NewAIWithConfig(DefaultAIConfig())
+	if err != nil {
+		panic(err)
+	}
+	return ai
+}
+
+// NewAIWithConfig returns an AI with the given configuration.
+// Returns an error if the configuration names an unknown engine or evaluator,
+// or if the search depth is less than one.
+func NewAIWithConfig(c AIConfig) (*AI, error) {
+	if c.Depth < 1 {
+		return nil, errors.New("AI: depth must be at least 1")
+	}
+	engine, ok := Engines[c.Engine]
+	if !ok {
+		return nil, fmt.Errorf("AI: unknown engine %q", c.Engine)
+	}
+	eval, ok := Evaluators[c.Evaluator]
+	if !ok {
+		return nil, fmt.Errorf("AI: unknown evaluator %q", c.Evaluator)
+	}
 	return &AI{
-		r:     rand.New(rand.NewSource(1)),
-		depth: 3,
-		trace: 0,
+		cfg:    c,
+		r:      rand.New(rand.NewSource(c.Seed)),
+		depth:  c.Depth,
+		qdepth: c.QuiescenceDepth,
+		trace:  c.Trace,
+		debug:  c.Debug,
+		eval:   eval,
+		engine: engine,
+	}, nil
+}
+
+// SetLogger sets the logger which receives events during a search.
+// If l is nil, which is the default, events are discarded.
+func (ai *AI) SetLogger(l Logger) {
+	ai.logger = l
+}
+
+func (ai *AI) log(e Event) {
+	if ai.logger != nil {
+		ai.logger.Log(e)
+	}
+}
+
+// SetHistory tells the AI which positions have already occurred in g
+// and which repetition policy to follow.
+// It should be called before each search.
+func (ai *AI) SetHistory(g *Game) {
+	ai.repetition = g.Repetition
+	seen := g.positions()
+	ai.history = make(map[string]int, len(seen))
+	for k, n := range seen {
+		ai.history[k] = n
 	}
 }
 
+// Config returns the configuration the AI was created with.
+func (ai *AI) Config() AIConfig {
+	return ai.cfg
+}
+
+// Search chooses an action using the configured engine.
+func (ai *AI) Search(pos Position, last BasicAction) (Action, float64) {
+	ai.reached = 0
+	ai.line = nil
+	return ai.engine(ai, pos, last)
+}
+
+// SetStop sets a channel which stops searches when it is closed,
+// as though their time ran out.
+// Only searches with a time limit can be stopped,
+// and the search to depth one is always finished.
+// If c is nil, which is the default, searches run to completion.
+func (ai *AI) SetStop(c <-chan struct{}) {
+	ai.stop = c
+}
+
+// Random chooses a random basic action.
+func (ai *AI) Random(pos Position, last BasicAction) (Action, float64) {
+	acts := pos.BasicActions()
+	a := acts[ai.r.Intn(len(acts))]
+	tmp := pos.do(a)
+	ai.line = []Action{a.Action()}
+	return a.Action(), ai.eval(tmp)
+}
+
+// Minimax searches the game tree to the configured depth
+// and returns the best action found and its score.
+//
+// If the AI has a time limit, the search deepens one ply at a time
+// and stops when time runs out, returning the result of the
+// deepest search which completed.
 func (ai *AI) Minimax(pos Position, last BasicAction) (Action, float64) {
 	t := time.Now()
 	ai.visited = 0
 	ai.evaluated = 0
 
+	var maxact Action
+	var max float64
+	if ai.cfg.TimeLimit <= 0 {
+		maxact, max = ai.root(pos, last, ai.depth)
+		ai.reached = ai.depth
+		ai.line = append([]Action(nil), ai.pv[1]...)
+	} else {
+		deadline := t.Add(ai.cfg.TimeLimit)
+		for depth := 1; depth <= ai.depth; depth++ {
+			// always finish the first ply
+			if depth > 1 {
+				ai.deadline = deadline
+			}
+			a, v := ai.root(pos, last, depth)
+			if ai.timeout {
+				break
+			}
+			maxact, max = a, v
+			ai.reached = depth
+			ai.line = append([]Action(nil), ai.pv[1]...)
+			ai.log(Event{
+				Type:      DepthDone,
+				Player:    pos.CurrentPlayer(),
+				Depth:     depth,
+				Action:    a,
+				Score:     v,
+				Visited:   ai.visited,
+				Evaluated: ai.evaluated,
+				Elapsed:   time.Since(t),
+			})
+		}
+		ai.deadline = time.Time{}
+		ai.timeout = false
+	}
+
+	ai.log(Event{
+		Type:      SearchDone,
+		Player:    pos.CurrentPlayer(),
+		Depth:     ai.reached,
+		Action:    maxact,
+		Score:     max,
+		Visited:   ai.visited,
+		Evaluated: ai.evaluated,
+		Elapsed:   time.Since(t),
+	})
+	return maxact, max
+}
+
+// PrincipalVariation returns the line of play the AI expected to follow
+// when its last search chose an action:
+// the action, the best reply to it, the best reply to that, and so on,
+// as far as the search looked.
+func (ai *AI) PrincipalVariation() []Action {
+	return append([]Action(nil), ai.line...)
+}
+
+// clearPV empties the principal variation from ply.
+func (ai *AI) clearPV(ply int) {
+	for len(ai.pv) <= ply+1 {
+		ai.pv = append(ai.pv, nil)
+	}
+	ai.pv[ply] = ai.pv[ply][:0]
+}
+
+// setPV makes a, followed by the line from ply+1,
+// the principal variation from ply.
+func (ai *AI) setPV(ply int, a Action) {
+	ai.pv[ply] = append(append(ai.pv[ply][:0], a), ai.pv[ply+1]...)
+}
+
+// expired reports whether the search has run out of time.
+func (ai *AI) expired() bool {
+	if ai.timeout {
+		return true
+	}
+	if ai.deadline.IsZero() || ai.visited&1023 != 0 {
+		return false
+	}
+	select {
+	case <-ai.stop:
+		ai.timeout = true
+	default:
+		ai.timeout = time.Now().After(ai.deadline)
+	}
+	return ai.timeout
+}
+
+func (ai *AI) root(pos Position, last BasicAction, depth int) (Action, float64) {
 	var maxact Action
 	min := 5.0
 	max := -5.0
-	depth := ai.depth
 	ply := 1
 
+	ai.clearPV(ply)
 	acts := pos.BasicActions()
 	shuffle(acts, ai.r)
-	log.Printf("%d basic actions to examine", len(acts))
+	ai.log(Event{Type: SearchStart, Player: pos.CurrentPlayer(), Depth: depth, Count: len(acts)})
 	for _, a := range acts {
+		if ai.expired() {
+			return maxact, max
+		}
 		tmp := pos.do(a)
 		if a.Type() == Attack && a == last {
-			fmt.Println("Action returns to an earlier state:", a)
+			ai.log(Event{Type: RepeatedPosition, Player: pos.CurrentPlayer(), Ply: ply, Depth: depth, Action: a.Action()})
 			continue
 		}
 		//tmp.catastrophes()
-		if ai.debug && !tmp.sanityCheck() {
-			fmt.Println("last action:", a)
+		if ai.debug && !ai.check(tmp, pos, ply, depth, a.Action()) {
 			continue
 		}
 		tmp.endturn()
-		v := -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min)
+		v, ok := ai.descend(tmp, pos, ply, depth, min, max)
+		if !ok {
+			continue
+		}
 		//fmt.Printf("%d %c %f\n", depth, "+-"[pos.player], v)
 		if ply <= ai.trace {
-			log.Printf("%*s player=%d ply=%d depth=%d v=%f min= max=%f move=%s", ply, "", pos.CurrentPlayer(), ply, depth, v, max, a)
+			ai.trace1(pos, ply, depth, v, min, max, a.Action())
 		}
 		if v > max {
 			max = v
 			maxact = a.Action()
+			ai.setPV(ply, maxact)
 		}
 	}
 
 	sacts := pos.SacrificeActions()
 	sshuffle(sacts, ai.r)
-	log.Printf("%d sacrifice actions to examine", len(sacts))
+	ai.log(Event{Type: SearchSacrifices, Player: pos.CurrentPlayer(), Depth: depth, Count: len(sacts)})
 	for _, a := range sacts {
+		if ai.expired() {
+			return maxact, max
+		}
 		tmp := pos.do(a.Basic())
 		for i := 0; i < a.N(); i++ {
 			tmp = tmp.do(a.actions[i])
 		}
 		tmp.endturn()
-		v := -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min)
+		v, ok := ai.descend(tmp, pos, ply, depth, min, max)
+		if !ok {
+			continue
+		}
 		if ply <= ai.trace {
-			log.Printf("%*s player=%d ply=%d depth=%d v=%f min= max=%f move=%s", ply, "", pos.CurrentPlayer(), ply, depth, v, max, a.Basic())
+			ai.trace1(pos, ply, depth, v, min, max, a)
 		}
 		if v > max {
 			max = v
 			maxact = a
+			ai.setPV(ply, maxact)
 		}
 	}
-
-	d := time.Since(t)
-	ms := float64(d) / float64(time.Millisecond)
-	log.Printf("visited=%d (%.1f/ms) evaluated=%d (%.1f/ms) in %s",
-		ai.visited, float64(ai.visited)/ms,
-		ai.evaluated, float64(ai.evaluated)/ms,
-		d)
 	return maxact, max
 }
 
 func (ai *AI) minimax(pos, last Position, ply, depth int, min, max float64) float64 {
+	if depth <= 0 {
+		return ai.quiesce(pos, last, ply, ai.qdepth, min, max)
+	}
 	ai.visited++
-	if pos.over() {
-		ai.evaluated++
-		return pos.score() * float64(depth+1)
+	if ai.expired() {
+		return 0
 	}
-	if depth <= 0 {
+	if pos.over() {
 		ai.evaluated++
-		return pos.score()
+		return ai.eval(pos) * float64(depth+1)
 	}
 
 	// basic actions
@@ -726,23 +994,25 @@ func (ai *AI) minimax(pos, last Position, ply, depth int, min, max float64) floa
 		tmp := pos.do(a)
 		if a.Type() == Attack && tmp.Equal(last) {
 			if ply == 2 {
-				fmt.Println("action returns to an earlier state:", a)
+				ai.log(Event{Type: RepeatedPosition, Player: pos.CurrentPlayer(), Ply: ply, Depth: depth, Action: a.Action()})
 			}
 			continue
 		}
-		if ai.debug && !tmp.sanityCheck() {
-			fmt.Println("pos:", tmp)
-			fmt.Println("last action:", a)
+		if ai.debug && !ai.check(tmp, pos, ply, depth, a.Action()) {
 			continue
 		}
 		//tmp.catastrophes()
 		tmp.endturn()
-		v := -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min)
+		v, ok := ai.descend(tmp, pos, ply, depth, min, max)
+		if !ok {
+			continue
+		}
 		if ply <= ai.trace {
-			log.Printf("%*s player=%d ply=%d depth=%d v=%f min=%f max=%f move=%s", ply, "", pos.CurrentPlayer(), ply, depth, v, min, max, a)
+			ai.trace1(pos, ply, depth, v, min, max, a.Action())
 		}
 		if v > max {
 			max = v
+			ai.setPV(ply, a.Action())
 		}
 		if max >= min {
 			return max
@@ -758,12 +1028,16 @@ func (ai *AI) minimax(pos, last Position, ply, depth int, min, max float64) floa
 			tmp = tmp.do(sa.actions[i])
 		}
 		tmp.endturn()
-		v := -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min)
+		v, ok := ai.descend(tmp, pos, ply, depth, min, max)
+		if !ok {
+			continue
+		}
 		if ply <= ai.trace {
-			log.Printf("%*s player=%d ply=%d depth=%d v=%f min=%f max=%f move=%s", ply, "", pos.CurrentPlayer(), ply, depth, v, min, max, sa.Basic())
+			ai.trace1(pos, ply, depth, v, min, max, sa)
 		}
 		if v > max {
 			max = v
+			ai.setPV(ply, sa)
 		}
 		if max >= min {
 			return max
@@ -772,6 +1046,232 @@ func (ai *AI) minimax(pos, last Position, ply, depth int, min, max float64) floa
 	return max
 }
 
+// descend searches the position reached by an action,
+// after the end of the turn, and returns its score
+// from the point of view of the player who took the action.
+// It returns false if the repetition policy forbids the position.
+func (ai *AI) descend(tmp, pos Position, ply, depth int, min, max float64) (float64, bool) {
+	ai.clearPV(ply + 1)
+	if ai.repetition == AllowRepetition {
+		return -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min), true
+	}
+	var buf [4 + 9*16]byte
+	b := tmp.appendKey(buf[:0])
+	n := ai.history[string(b)]
+	if ai.repetition == ForbidRepetition && n > 0 {
+		return 0, false
+	}
+	if ai.repetition == DrawByRepetition && n >= 2 {
+		ai.evaluated++
+		return 0, true
+	}
+	if depth <= 1 {
+		// leaf; no need to record the position
+		return -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min), true
+	}
+	k := string(b)
+	ai.history[k]++
+	v := -ai.minimax(tmp, pos, ply+1, depth-1, -max, -min)
+	ai.history[k]--
+	return v, true
+}
+
+// trace1 logs an action searched at the given ply.
+func (ai *AI) trace1(pos Position, ply, depth int, v, min, max float64, a Action) {
+	ai.log(Event{
+		Type:   SearchAction,
+		Player: pos.CurrentPlayer(),
+		Ply:    ply,
+		Depth:  depth,
+		Action: a,
+		Score:  v,
+		Min:    min,
+		Max:    max,
+	})
+}
+
+// check runs a sanity check on the position reached by a
+// and logs any failure.
+func (ai *AI) check(pos, last Position, ply, depth int, a Action) bool {
+	err := pos.Check()
+	if err != nil {
+		ai.log(Event{Type: SanityFailure, Player: last.CurrentPlayer(), Ply: ply, Depth: depth, Action: a, Err: err})
+		return false
+	}
+	return true
+}
+
+// quiesce extends the search past the horizon.
+// Rather than trusting the static score of a position
+// where a capture or catastrophe is about to happen,
+// it keeps following noisy actions until the position is quiet
+// or qdepth runs out.
+//
+// Noisy actions are attacks, actions which cause a catastrophe,
+// and red or yellow sacrifices which threaten the enemy homeworld.
+// Since a player can always decline to make a noisy action,
+// the static score serves as a lower bound (standing pat).
+func (ai *AI) quiesce(pos, last Position, ply, qdepth int, min, max float64) float64 {
+	ai.visited++
+	if ai.expired() {
+		return 0
+	}
+	ai.evaluated++
+	v := ai.eval(pos)
+	if pos.over() || qdepth <= 0 {
+		return v
+	}
+	if v > max {
+		max = v
+	}
+	if max >= min {
+		return max
+	}
+	colors := pos.threatColors()
+	if colors == 0 && pos.quiet() {
+		return max
+	}
+
+	for _, a := range pos.BasicActions() {
+		if !pos.noisy(a) {
+			continue
+		}
+		tmp := pos.do(a)
+		if a.Type() == Attack && tmp.Equal(last) {
+			continue
+		}
+		tmp.endturn()
+		v := -ai.quiesce(tmp, pos, ply+1, qdepth-1, -max, -min)
+		if v > max {
+			max = v
+		}
+		if max >= min {
+			return max
+		}
+	}
+
+	sg := sacrificeGenerator{colors: colors}
+	if sg.colors == 0 {
+		return max
+	}
+	for _, sa := range sg.Generate(pos) {
+		if !pos.threatens(sa) {
+			continue
+		}
+		tmp := pos.do(sa.Basic())
+		for i := 0; i < sa.N(); i++ {
+			tmp = tmp.do(sa.actions[i])
+		}
+		tmp.endturn()
+		v := -ai.quiesce(tmp, pos, ply+1, qdepth-1, -max, -min)
+		if v > max {
+			max = v
+		}
+		if max >= min {
+			return max
+		}
+	}
+	return max
+}
+
+// noisy reports whether b is an attack or causes a catastrophe.
+func (pos Position) noisy(b BasicAction) bool {
+	if b.Type() == Attack {
+		return true
+	}
+	_, _, ok := pos.catastrophe(b)
+	return ok
+}
+
+// quiet reports whether the current player has no basic action which is noisy,
+// without generating them: no system they could attack in,
+// and no system with three pieces of a color.
+func (pos Position) quiet() bool {
+	pl := pos.CurrentPlayer()
+	for id := range pos.stars {
+		s := &pos.stars[id]
+		for c := Color(0); c < Color(4); c++ {
+			if s.population(c) >= 3 {
+				return false
+			}
+		}
+		ships := s.Ships(pl)
+		if ships.IsEmpty() || s.OtherShips(pl).IsEmpty() {
+			continue
+		}
+		if s.pieces.HasColor(Red) || ships.HasColor(Red) {
+			return false
+		}
+	}
+	return true
+}
+
+// catastrophe reports whether b would overpopulate a system,
+// and if so, which system and color.
+func (pos Position) catastrophe(b BasicAction) (id int, c Color, ok bool) {
+	switch b.Type() {
+	case Build:
+		id, c = b.System(), b.Ship().Color()
+	case Trade:
+		id, c = b.System(), b.NewShip().Color()
+	case Move:
+		id, c = b.ToSystem(), b.Ship().Color()
+	default:
+		// discovered systems only have two pieces
+		return 0, 0, false
+	}
+	if pos.stars[id].population(c)+1 >= 4 {
+		return id, c, true
+	}
+	return 0, 0, false
+}
+
+// population returns the number of pieces of the given color in the system.
+func (s *Dwarf) population(c Color) int {
+	return s.pieces.ColorCount(c) + s.ships[0].ColorCount(c) + s.ships[1].ColorCount(c)
+}
+
+// threatColors returns the set of colors (1<<color)
+// which the current player could sacrifice to threaten the enemy homeworld:
+// red if they have ships there which could attack,
+// and yellow if any of their ships are next to it.
+func (pos Position) threatColors() uint {
+	var colors uint
+	pl := pos.CurrentPlayer()
+	home := &pos.stars[pl&1^1]
+	if !home.Ships(pl).IsEmpty() && !home.OtherShips(pl).IsEmpty() {
+		colors |= 1 << Red
+	}
+	for id := range pos.stars {
+		s := &pos.stars[id]
+		if !s.Ships(pl).IsEmpty() && s.Connects(home) {
+			colors |= 1 << Yellow
+			break
+		}
+	}
+	return colors
+}
+
+// threatens reports whether a sacrifice action
+// attacks or moves into the enemy homeworld.
+func (pos Position) threatens(a Action) bool {
+	home := int(pos.player&1 ^ 1)
+	for i := 0; i < a.N(); i++ {
+		b := a.actions[i]
+		switch b.Type() {
+		case Attack:
+			if b.System() == home {
+				return true
+			}
+		case Move:
+			if b.ToSystem() == home {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (pos Position) over() bool {
 	return pos.stars[North].ships[North].IsEmpty() || pos.stars[South].ships[South].IsEmpty()
 }
@@ -863,6 +1363,28 @@ func (pos Position) score() float64 {
 	return score
 }
 
+// materialScore counts only the size of each player's fleet.
+func (pos Position) materialScore() float64 {
+	if pos.over() {
+		return pos.score()
+	}
+	v := 0
+	for _, s := range pos.stars {
+		for it := s.ships[North].Iter(); !it.Done(); it.Next() {
+			v += points[it.Piece().Size()] * it.Count()
+		}
+		for it := s.ships[South].Iter(); !it.Done(); it.Next() {
+			v -= points[it.Piece().Size()] * it.Count()
+		}
+	}
+	const max = (1 + 3 + 9) * 12
+	score := float64(v) / (max + 1)
+	if pos.player == 1 {
+		score = -score
+	}
+	return score
+}
+
 func sshuffle(acts []Action, r *rand.Rand) {
 	for i := 0; 1 < len(acts)-i; i++ {
 		j := i + r.Intn(len(acts)-i)
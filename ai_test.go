@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 	"unsafe"
 )
 
@@ -63,3 +64,156 @@ func BenchmarkMinimax(b *testing.B) {
 		ai.Minimax(pos, BasicAction{})
 	}
 }
+
+func TestNoisy(t *testing.T) {
+	// North has three greens at their homeworld,
+	// so building or trading for a fourth causes a catastrophe.
+	g := &Game{
+		NumPlayers:    2,
+		CurrentPlayer: North,
+		Homeworlds:    map[Player]string{North: "north", South: "south"},
+		Stars: map[string]*Star{
+			"north": {Name: "north", IsHomeworld: true, Pieces: []Piece{G3, Y1}, Ships: map[Player][]Piece{North: {G1, G2, B3}}},
+			"south": {Name: "south", IsHomeworld: true, Pieces: []Piece{Y3, B2}, Ships: map[Player][]Piece{South: {G3}}},
+		},
+	}
+	g.ResetBank()
+	pos := PositionFromGame(g)
+	for _, a := range pos.BasicActions() {
+		want := a.Type() == Build && a.Ship().Color() == Green ||
+			a.Type() == Trade && a.NewShip().Color() == Green
+		if got := pos.noisy(a); got != want {
+			t.Errorf("noisy(%v) = %v, want %v", a, got, want)
+		}
+	}
+}
+
+func TestParseAIConfig(t *testing.T) {
+	c := DefaultAIConfig()
+	c.Depth = 5
+	c.TimeLimit = 2 * time.Second
+	c.Engine = "random"
+	got, err := ParseAIConfig(c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != c {
+		t.Errorf("ParseAIConfig(%q) = %v", c.String(), got)
+	}
+	if _, err := ParseAIConfig("depth=3,eval=bogus"); err == nil {
+		t.Errorf("expected error for unknown evaluator")
+	}
+	if _, err := ParseAIConfig("depth=0"); err == nil {
+		t.Errorf("expected error for depth 0")
+	}
+}
+
+func TestChooseHomeworld(t *testing.T) {
+	// The AI names its homeworld after its player,
+	// as the other frontends do.
+	g := NewGame(2)
+	ai := NewAI()
+	for _, pl := range []Player{North, South} {
+		turn, err := ai.ChooseHomeworld(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Play(turn); err != nil {
+			t.Fatal(err)
+		}
+		if got := g.Homeworlds[pl]; got != pl.String() {
+			t.Errorf("%s's homeworld is called %q, want %q", pl, got, pl.String())
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var events []Event
+	ai := NewAI()
+	ai.SetLogger(LoggerFunc(func(e Event) {
+		events = append(events, e)
+	}))
+	a, v := ai.Minimax(PositionFromGame(game), BasicAction{})
+	if len(events) == 0 {
+		t.Fatal("no events logged")
+	}
+	last := events[len(events)-1]
+	if last.Type != SearchDone || last.Action != a || last.Score != v {
+		t.Errorf("last event = %+v, want SearchDone %v %v", last, a, v)
+	}
+}
+
+func TestLoggerStopped(t *testing.T) {
+	// A search which is stopped early reports the depth it reached.
+	cfg := DefaultAIConfig()
+	cfg.Depth = 4
+	cfg.TimeLimit = time.Hour
+	ai, err := NewAIWithConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	close(stop)
+	ai.SetStop(stop)
+	var last Event
+	ai.SetLogger(LoggerFunc(func(e Event) {
+		last = e
+	}))
+	ai.Search(PositionFromGame(game), BasicAction{})
+	if last.Type != SearchDone || last.Depth != 1 {
+		t.Errorf("last event has type %d and depth %d, want SearchDone (%d) at depth 1", last.Type, last.Depth, SearchDone)
+	}
+}
+
+func TestQuiescence(t *testing.T) {
+	// Building a third green at North's homeworld gains a ship,
+	// but it lets South move a fourth green in from alpha,
+	// which destroys every ship North has.
+	// A depth one search only sees the threat if it looks past the horizon.
+	g := &Game{
+		NumPlayers:    2,
+		CurrentPlayer: North,
+		Homeworlds:    map[Player]string{North: "north", South: "south"},
+		Stars: map[string]*Star{
+			"north": {Name: "north", IsHomeworld: true, Pieces: []Piece{G3, Y1}, Ships: map[Player][]Piece{North: {G2}}},
+			"south": {Name: "south", IsHomeworld: true, Pieces: []Piece{Y3, B2}, Ships: map[Player][]Piece{South: {R3}}},
+			"alpha": {Name: "alpha", Pieces: []Piece{Y2}, Ships: map[Player][]Piece{South: {G1}}},
+		},
+	}
+	g.ResetBank()
+	pos := PositionFromGame(g)
+	blunder := func(a Action) bool {
+		return a.Type() == Build && a.System() == int(North) && a.Ship().Color() == Green
+	}
+	for _, qdepth := range []int{0, 2} {
+		c := DefaultAIConfig()
+		c.Depth = 1
+		c.QuiescenceDepth = qdepth
+		c.Evaluator = "material"
+		ai, err := NewAIWithConfig(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, v := ai.Minimax(pos, BasicAction{})
+		if want := qdepth == 0; blunder(a) != want {
+			t.Errorf("qdepth %d: chose %v (score %.3f); blunder = %v, want %v", qdepth, a, v, blunder(a), want)
+		}
+	}
+}
+
+func TestPrincipalVariation(t *testing.T) {
+	ai := NewAI()
+	pos := PositionFromGame(game)
+	a, _ := ai.Search(pos, BasicAction{})
+	pv := ai.PrincipalVariation()
+	if len(pv) != ai.depth || pv[0] != a {
+		t.Fatalf("searched to depth %d and chose %v, but the principal variation is %v", ai.depth, a, pv)
+	}
+	// each action must be legal in turn
+	for i, b := range pv {
+		if !pos.legal(b) {
+			t.Fatalf("action %d of the principal variation, %v, is not legal", i, b)
+		}
+		pos = pos.Do(b)
+	}
+}
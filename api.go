@@ -0,0 +1,221 @@
+package homeworlds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// An APIHandler serves an HTTP interface to the rules and the AI.
+// Each endpoint takes a POST request with a JSON body
+// and replies with a JSON object.
+//
+// Positions are given as position strings (see Position.FEN).
+// Actions are encoded as described under JSONVersion;
+// their system numbers count the stars in the order
+// they are listed in the position string, starting from zero.
+//
+// POST /moves lists the legal actions in a position:
+//
+//	{"position": "233/332/331/313 @N=R1B2:G3:- @S=Y3B2:-:G3 N"}
+//	→ {"basic": [{"type": "build", ...}, ...], "sacrifice": [...]}
+//
+// POST /apply plays an action and returns the resulting position,
+// in which it is the other player's turn:
+//
+//	{"position": "...", "action": {"type": "build", "system": 0, "ship": "G1"}}
+//	→ {"position": "...", "over": false}
+//
+// POST /search asks the AI for the best action, its score,
+// and the principal variation: the line of play the AI expects to follow.
+// The options are AI settings as accepted by AIConfig.Set;
+// the AI starts from the handler's configuration.
+// The optional last action is the opponent's previous action.
+//
+//	{"position": "...", "options": {"depth": 3, "time": "2s"}, "last": {...}}
+//	→ {"action": {...}, "score": 0.25, "pv": [{...}, ...]}
+//
+// Errors are reported with a status of 400 Bad Request
+// and a body like {"error": "..."}.
+type APIHandler struct {
+	// Config is the AI configuration used by searches
+	// before the request's options are applied.
+	Config AIConfig
+
+	// MaxDepth is the greatest search depth a request may ask for.
+	// Zero means there is no limit.
+	MaxDepth int
+
+	// MaxQuiescenceDepth is the greatest quiescence search depth
+	// a request may ask for.
+	// Zero means there is no limit.
+	MaxQuiescenceDepth int
+
+	// MaxTime is the longest time limit a request may ask for.
+	// Searches without a time limit are given this one.
+	// Zero means there is no limit.
+	MaxTime time.Duration
+}
+
+// NewAPIHandler returns an APIHandler which searches
+// with the default AI configuration.
+func NewAPIHandler() *APIHandler {
+	return &APIHandler{Config: DefaultAIConfig()}
+}
+
+// maxRequestSize limits the size of a request body.
+const maxRequestSize = 1 << 20
+
+type apiRequest struct {
+	Position *Position              `json:"position"`
+	Action   *Action                `json:"action"`
+	Last     *BasicAction           `json:"last"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+type apiMoves struct {
+	Basic     []BasicAction `json:"basic"`
+	Sacrifice []Action      `json:"sacrifice"`
+}
+
+type apiApply struct {
+	Position Position `json:"position"`
+	Over     bool     `json:"over"`
+}
+
+type apiSearch struct {
+	Action Action   `json:"action"`
+	Score  float64  `json:"score"`
+	PV     []Action `json:"pv"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var serve func(*apiRequest) (interface{}, error)
+	switch r.URL.Path {
+	case "/moves":
+		serve = h.moves
+	case "/apply":
+		serve = h.apply
+	case "/search":
+		serve = h.search
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+		return
+	}
+	var req apiRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	if req.Position == nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"missing position"})
+		return
+	}
+	v, err := serve(&req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (h *APIHandler) moves(req *apiRequest) (interface{}, error) {
+	pos := *req.Position
+	if pos.over() {
+		return apiMoves{Basic: []BasicAction{}, Sacrifice: []Action{}}, nil
+	}
+	v := apiMoves{
+		Basic:     pos.BasicActions(),
+		Sacrifice: pos.SacrificeActions(),
+	}
+	if v.Sacrifice == nil {
+		v.Sacrifice = []Action{}
+	}
+	return v, nil
+}
+
+func (h *APIHandler) apply(req *apiRequest) (interface{}, error) {
+	pos := *req.Position
+	if req.Action == nil {
+		return nil, fmt.Errorf("missing action")
+	}
+	if pos.over() {
+		return nil, fmt.Errorf("the game is over")
+	}
+	if !pos.legal(*req.Action) {
+		return nil, fmt.Errorf("illegal action: %s", req.Action)
+	}
+	pos = pos.Do(*req.Action)
+	return apiApply{Position: pos, Over: pos.over()}, nil
+}
+
+func (h *APIHandler) search(req *apiRequest) (interface{}, error) {
+	pos := *req.Position
+	if pos.over() {
+		return nil, fmt.Errorf("the game is over")
+	}
+	cfg := h.Config
+	for name, value := range req.Options {
+		if err := cfg.Set(name, fmt.Sprint(value)); err != nil {
+			return nil, err
+		}
+	}
+	if h.MaxDepth > 0 && cfg.Depth > h.MaxDepth {
+		return nil, fmt.Errorf("depth %d is greater than the limit of %d", cfg.Depth, h.MaxDepth)
+	}
+	if h.MaxQuiescenceDepth > 0 && cfg.QuiescenceDepth > h.MaxQuiescenceDepth {
+		return nil, fmt.Errorf("qdepth %d is greater than the limit of %d", cfg.QuiescenceDepth, h.MaxQuiescenceDepth)
+	}
+	if h.MaxTime > 0 {
+		if cfg.TimeLimit > h.MaxTime {
+			return nil, fmt.Errorf("time %v is greater than the limit of %v", cfg.TimeLimit, h.MaxTime)
+		}
+		if cfg.TimeLimit <= 0 {
+			cfg.TimeLimit = h.MaxTime
+		}
+	}
+	ai, err := NewAIWithConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var last BasicAction
+	if req.Last != nil {
+		last = *req.Last
+	}
+	a, v := ai.Search(pos, last)
+	return apiSearch{Action: a, Score: v, PV: ai.PrincipalVariation()}, nil
+}
+
+// legal reports whether a is one of the actions available in pos.
+func (pos Position) legal(a Action) bool {
+	if a.Type() == Sacrifice {
+		for _, s := range pos.SacrificeActions() {
+			if s == a {
+				return true
+			}
+		}
+		return false
+	}
+	for _, b := range pos.BasicActions() {
+		if b.Action() == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package homeworlds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIHandler(t *testing.T) {
+	const fen = "233/332/331/313 @N=R1B2:G3:- @S=Y3B2:-:G3 N"
+	pos, err := ParsePosition(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAPIHandler()
+	h.MaxDepth = 2
+	h.MaxQuiescenceDepth = 4
+	h.MaxTime = time.Minute
+	post := func(path, body string, wantCode int, v interface{}) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		if w.Code != wantCode {
+			t.Fatalf("POST %s %s: got status %d, want %d: %s", path, body, w.Code, wantCode, w.Body)
+		}
+		if v != nil {
+			if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Fatalf("POST %s: %v", path, err)
+			}
+		}
+	}
+
+	var moves struct {
+		Basic     []BasicAction
+		Sacrifice []Action
+	}
+	post("/moves", `{"position": "`+fen+`"}`, 200, &moves)
+	if len(moves.Basic) != len(pos.BasicActions()) || len(moves.Sacrifice) != len(pos.SacrificeActions()) {
+		t.Errorf("/moves: got %d basic and %d sacrifice actions, want %d and %d",
+			len(moves.Basic), len(moves.Sacrifice), len(pos.BasicActions()), len(pos.SacrificeActions()))
+	}
+
+	var applied struct {
+		Position Position
+		Over     bool
+	}
+	post("/apply", `{"position": "`+fen+`", "action": {"type": "build", "system": 0, "ship": "G1"}}`, 200, &applied)
+	if want := pos.Do(mkbasic(Build, G1, 0, 0).Action()); !applied.Position.Equal(want) || applied.Over {
+		t.Errorf("/apply: got %v (over %v), want %v", applied.Position, applied.Over, want)
+	}
+	post("/apply", `{"position": "`+fen+`", "action": {"type": "build", "system": 1, "ship": "G1"}}`, 400, nil)
+	var e struct{ Error string }
+	post("/apply", `{"position": "233/333/233/323 @N=R1:-:- @S=B2:-:G1 N", "action": {"type": "pass"}}`, 400, &e)
+	if e.Error != "the game is over" {
+		t.Errorf("/apply in a finished game: got error %q", e.Error)
+	}
+
+	var result struct {
+		Action Action
+		PV     []Action
+	}
+	post("/search", `{"position": "`+fen+`", "options": {"depth": 2}}`, 200, &result)
+	if len(result.PV) != 2 || result.PV[0] != result.Action {
+		t.Errorf("/search: got action %v and principal variation %v", result.Action, result.PV)
+	}
+	post("/search", `{"position": "`+fen+`", "options": {"depth": 3}}`, 400, nil)
+	post("/search", `{"position": "`+fen+`", "options": {"qdepth": 5}}`, 400, nil)
+	post("/search", `{"position": "`+fen+`", "options": {"time": "2m"}}`, 400, nil)
+	post("/search", `{"options": {}}`, 400, nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/moves", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /moves: got status %d", w.Code)
+	}
+}
@@ -1,50 +1,212 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/magical/homeworlds"
 )
 
-var systemid = 2
-
 func main() {
+	cfg := homeworlds.DefaultAIConfig()
+	cfg.AddFlags(flag.CommandLine)
+	repetition := homeworlds.DrawByRepetition
+	flag.Var(&repetition, "repetition", "repeated position `policy` (allow, draw, forbid)")
+	board := flag.String("board", "prose", "board `style` (prose, grid)")
+	replay := flag.String("replay", "", "write an animated GIF of the game to `file`")
+	frames := flag.String("frames", "", "write an SVG image of each ply to `dir`")
+	names := flag.Int64("names", 0, "shuffle star names with the given `seed` (0 for the default order)")
+	north := flag.String("north", "", "run the external engine `command` as North instead of the AI")
+	south := flag.String("south", "", "run the external engine `command` as South instead of the AI")
+	var players contestants
+	flag.Var(&players, "player", "add a tournament `player`: name=ai-options or name=engine:command (repeatable)")
+	format := flag.String("format", "roundrobin", "tournament `format` (roundrobin, gauntlet)")
+	openings := flag.String("openings", "", "read tournament openings from `file`, one position per line")
+	rounds := flag.Int("rounds", 1, "play each tournament pairing and opening `n` times with each color")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "play `n` tournament games at once")
+	records := flag.String("records", "", "write the record of each tournament game to `dir`")
+	maxTurns := flag.Int("maxturns", 200, "adjudicate tournament games as drawn after `n` turns")
+	sprt := flag.String("sprt", "", "stop a two-player tournament once an SPRT between `elo0,elo1` decides\n(-rounds sets the most games to play)")
+	alpha := flag.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := flag.Float64("beta", 0.05, "SPRT false negative rate")
+	flag.Parse()
+
+	if len(players) > 0 {
+		t := &tournament{
+			players:    players,
+			openings:   []string{newGame().FEN()},
+			rounds:     *rounds,
+			parallel:   *parallel,
+			maxTurns:   *maxTurns,
+			records:    *records,
+			config:     cfg,
+			repetition: repetition,
+		}
+		switch *format {
+		case "roundrobin":
+		case "gauntlet":
+			t.gauntlet = true
+		default:
+			fmt.Fprintf(os.Stderr, "unknown tournament format %q\n", *format)
+			os.Exit(2)
+		}
+		if *openings != "" {
+			var err error
+			if t.openings, err = readOpenings(*openings); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		if *sprt != "" {
+			var elo0, elo1 float64
+			if _, err := fmt.Sscanf(*sprt, "%g,%g", &elo0, &elo1); err != nil || elo0 >= elo1 {
+				fmt.Fprintf(os.Stderr, "-sprt %q: want elo0,elo1 with elo0 < elo1\n", *sprt)
+				os.Exit(2)
+			}
+			if len(players) != 2 {
+				fmt.Fprintln(os.Stderr, "-sprt needs exactly two players")
+				os.Exit(2)
+			}
+			if !(*alpha > 0 && *alpha < 1 && *beta > 0 && *beta < 1) {
+				fmt.Fprintln(os.Stderr, "-alpha and -beta must be between 0 and 1")
+				os.Exit(2)
+			}
+			t.sprt = &homeworlds.SPRT{Elo0: elo0, Elo1: elo1, Alpha: *alpha, Beta: *beta}
+		}
+		if *rounds < 1 || *parallel < 1 {
+			fmt.Fprintln(os.Stderr, "-rounds and -parallel must be at least 1")
+			os.Exit(2)
+		}
+		if err := t.run(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	printBoard, err := homeworlds.BoardPrinter(*board, isTerminal(os.Stdout))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ai, err := homeworlds.NewAIWithConfig(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ai.SetLogger(homeworlds.NewTextLogger(os.Stderr))
+	var engines [2]*homeworlds.ExternalEngine
+	for i, command := range []string{*north, *south} {
+		if command == "" {
+			continue
+		}
+		engines[i], err = startEngine(command)
+		if err == nil {
+			err = engines[i].SetOption("repetition", repetition.String())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer engines[i].Close()
+	}
+
 	g := newGame()
-	ai := homeworlds.NewAI()
+	g.Repetition = repetition
+	if *names != 0 {
+		g.Namer = homeworlds.ShuffledStarNames(*names)
+	}
 	turn := 1
 	var last homeworlds.Action
 	for !g.IsOver() {
 		fmt.Println("\nTurn number", turn)
-		homeworlds.Print(os.Stdout, g)
+		printBoard(os.Stdout, g)
 		//actions := g.BasicActions()
 		//n := rand.Intn(len(actions))
 		//a := actions[n]
+		if e := engines[g.CurrentPlayer]; e != nil {
+			// External engines are held to the AI's limits.
+			t, err := e.Think(g, cfg.Depth, cfg.TimeLimit)
+			if err == nil {
+				fmt.Println("Turn:", g.FormatTurn(t), "Engine:", e.Name)
+				err = g.Play(t)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s engine: %v\n", g.CurrentPlayer, err)
+				os.Exit(1)
+			}
+			last = homeworlds.Action{}
+			turn++
+			continue
+		}
 		pos := homeworlds.PositionFromGame(g)
-		a, v := ai.Minimax(pos, last.Basic())
-		fmt.Println("Action:", a.Basic(), "Score:", v)
-		do(g, a)
+		ai.SetHistory(g)
+		a, v := ai.Search(pos, last.Basic())
+		fmt.Println("Action:", g.FormatAction(a), "Score:", v)
+		if err := g.Apply(a); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		last = a
 		turn++
 	}
-	if g.IsOver() {
+	if g.IsDraw() {
+		fmt.Println("Draw by repetition")
+	} else if g.IsOver() {
 		fmt.Println("Winner:", g.Winner())
 	}
+	if *replay != "" {
+		if err := writeReplay(*replay, g); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *frames != "" {
+		if err := writeFrames(*frames, g); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 }
 
-func starMap(g *homeworlds.Game) []*homeworlds.Star {
-	stars := g.SortedStars()
-	m := make([]*homeworlds.Star, 2, len(g.Stars))
-	m[0] = g.Homeworld(homeworlds.North)
-	m[1] = g.Homeworld(homeworlds.South)
-	for _, name := range stars {
-		s := g.Stars[name]
-		if s.IsHomeworld {
-			continue
-		}
-		m = append(m, s)
+// startEngine runs an external engine.
+// The command is split into words at spaces.
+func startEngine(command string) (*homeworlds.ExternalEngine, error) {
+	f := strings.Fields(command)
+	e, err := homeworlds.StartEngine(f[0], f[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	e.Info = func(line string) {
+		fmt.Fprintln(os.Stderr, line)
 	}
-	return m
+	return e, nil
+}
+
+func writeReplay(filename string, g *homeworlds.Game) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := homeworlds.RenderReplay(f, g, 150); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeFrames(dir string, g *homeworlds.Game) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	return homeworlds.RenderFrames(g, "svg", func(ply int) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, fmt.Sprintf("ply%03d.svg", ply)))
+	})
 }
 
 func newGame() *homeworlds.Game {
@@ -79,69 +241,13 @@ func newGame() *homeworlds.Game {
 		},
 	}
 	game.ResetBank()
+	game.ClearHistory()
 	return game
 }
 
-func do(g *homeworlds.Game, a homeworlds.Action) error {
-	err := doBasic(g, a.Basic())
-	if err != nil {
-		return err
-	}
-	catastrophe(g)
-
-	if a.Type() == homeworlds.Sacrifice {
-		for i := 0; i < a.N(); i++ {
-			err := doBasic(g, a.Action(i))
-			if err != nil {
-				return err
-			}
-			catastrophe(g)
-		}
-	}
-
-	g.EndTurn()
-	return nil
-}
-
-func doBasic(g *homeworlds.Game, a homeworlds.BasicAction) error {
-	stars := starMap(g)
-	if a.System() >= len(stars) {
-		return fmt.Errorf("no such system %d", a.System())
-	}
-	star := stars[a.System()]
-	switch a.Type() {
-	case homeworlds.Pass:
-		return nil
-	case homeworlds.Build:
-		return g.Build(a.Ship(), star)
-	case homeworlds.Trade:
-		return g.Trade(a.Ship(), star, a.NewShip())
-	case homeworlds.Move:
-		if a.ToSystem() >= len(stars) {
-			return fmt.Errorf("no such system %d", a.ToSystem())
-		}
-		toStar := stars[a.ToSystem()]
-		return g.Move(a.Ship(), star, toStar)
-	case homeworlds.Attack:
-		target := homeworlds.North
-		if g.CurrentPlayer == homeworlds.North {
-			target = homeworlds.South
-		}
-		return g.Attack(a.Ship(), star, target)
-	case homeworlds.Discover:
-		name := fmt.Sprint(systemid)
-		systemid++
-		return g.Discover(a.Ship(), star, a.NewShip(), name)
-	case homeworlds.Sacrifice:
-		return g.Sacrifice(a.Ship(), star)
-	}
-	return nil
-}
-
-func catastrophe(g *homeworlds.Game) {
-	for c := homeworlds.Color(0); c < homeworlds.Color(4); c++ {
-		for _, s := range g.Stars {
-			g.Catastrophe(c, s)
-		}
-	}
+// isTerminal reports whether f is a terminal,
+// which can show colors.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
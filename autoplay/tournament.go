@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/magical/homeworlds"
+)
+
+// A contestant is a player in a tournament:
+// either the AI with some options, or an external engine.
+type contestant struct {
+	name    string
+	options string // AI options, as for ParseAIConfig
+	command string // external engine command, if not empty
+}
+
+// contestants is a flag.Value which collects the -player flags.
+type contestants []contestant
+
+func (l *contestants) String() string {
+	var names []string
+	for _, c := range *l {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Set parses a player in the form name=options or name=engine:command.
+func (l *contestants) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("player %q: want name=options or name=engine:command", s)
+	}
+	c := contestant{name: s[:i]}
+	for _, d := range *l {
+		if d.name == c.name {
+			return fmt.Errorf("player %q: name is taken", c.name)
+		}
+	}
+	if spec := s[i+1:]; strings.HasPrefix(spec, "engine:") {
+		c.command = strings.TrimPrefix(spec, "engine:")
+		if strings.TrimSpace(c.command) == "" {
+			return fmt.Errorf("player %q: missing engine command", c.name)
+		}
+	} else {
+		c.options = spec
+	}
+	*l = append(*l, c)
+	return nil
+}
+
+// config returns the contestant's AI configuration,
+// which starts from base.
+func (c contestant) config(base homeworlds.AIConfig) (homeworlds.AIConfig, error) {
+	cfg, err := homeworlds.ParseAIOptions(base, c.options)
+	if err != nil {
+		return cfg, fmt.Errorf("player %q: %v", c.name, err)
+	}
+	return cfg, nil
+}
+
+// A seat plays one side of a game.
+type seat interface {
+	think(g *homeworlds.Game) (homeworlds.Turn, error)
+	Close() error
+}
+
+type aiSeat struct {
+	ai *homeworlds.AI
+}
+
+func (s aiSeat) think(g *homeworlds.Game) (homeworlds.Turn, error) {
+	if g.Homeworld(g.CurrentPlayer) == nil {
+		return s.ai.ChooseHomeworld(g)
+	}
+	s.ai.SetHistory(g)
+	a, _ := s.ai.Search(homeworlds.PositionFromGame(g), homeworlds.BasicAction{})
+	return g.ActionTurn(a)
+}
+
+func (s aiSeat) Close() error { return nil }
+
+// An engineSeat is an external engine,
+// which searches to the depth and time limit of the tournament's AI settings.
+type engineSeat struct {
+	*homeworlds.ExternalEngine
+	depth int
+	time  time.Duration
+}
+
+func (s engineSeat) think(g *homeworlds.Game) (homeworlds.Turn, error) {
+	return s.Think(g, s.depth, s.time)
+}
+
+// seat starts the contestant for a game.
+// The AI's seed is offset by the game number
+// so that games from the same opening can differ.
+func (c contestant) seat(base homeworlds.AIConfig, game int, repetition homeworlds.RepetitionPolicy) (seat, error) {
+	if c.command != "" {
+		f := strings.Fields(c.command)
+		e, err := homeworlds.StartEngine(f[0], f[1:]...)
+		if err != nil {
+			return nil, fmt.Errorf("player %q: %v", c.name, err)
+		}
+		if err := e.SetOption("repetition", repetition.String()); err != nil {
+			e.Close()
+			return nil, fmt.Errorf("player %q: %v", c.name, err)
+		}
+		return engineSeat{e, base.Depth, base.TimeLimit}, nil
+	}
+	base.Seed += int64(game)
+	cfg, err := c.config(base)
+	if err != nil {
+		return nil, err
+	}
+	ai, err := homeworlds.NewAIWithConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("player %q: %v", c.name, err)
+	}
+	return aiSeat{ai}, nil
+}
+
+// A tournament plays games between each pair of contestants,
+// with both colors, from each opening.
+type tournament struct {
+	players    contestants
+	gauntlet   bool // play only the first player against the others
+	openings   []string
+	rounds     int
+	parallel   int
+	maxTurns   int
+	records    string // directory for game records
+	config     homeworlds.AIConfig
+	repetition homeworlds.RepetitionPolicy
+
+	// sprt, if set, stops a match between two players
+	// once the first is shown to be stronger or not.
+	sprt *homeworlds.SPRT
+}
+
+// A match is one game of a tournament.
+type match struct {
+	num          int // numbered from 1
+	round        int
+	opening      int
+	north, south int // indexes into players
+}
+
+// An outcome is the result of a match.
+type outcome struct {
+	match
+	score  float64 // North's score: 1 for a win, 0.5 for a draw
+	reason string
+	err    error
+}
+
+// result formats the outcome in the style of PGN.
+func (o outcome) result() string {
+	switch o.score {
+	case 1:
+		return "1-0"
+	case 0:
+		return "0-1"
+	}
+	return "1/2-1/2"
+}
+
+// readOpenings reads position strings from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+// The word startpos stands for an empty board.
+func readOpenings(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var openings []string
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line != "startpos" {
+			if _, err := homeworlds.ParseFEN(line); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filename, n, err)
+			}
+		}
+		openings = append(openings, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("%s: no openings", filename)
+	}
+	return openings, nil
+}
+
+// matches lists the games of the tournament.
+func (t *tournament) matches() []match {
+	var pairs [][2]int
+	for i := range t.players {
+		for j := i + 1; j < len(t.players); j++ {
+			if t.gauntlet && i > 0 {
+				break
+			}
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	var ms []match
+	for r := 1; r <= t.rounds; r++ {
+		for _, p := range pairs {
+			for o := range t.openings {
+				ms = append(ms,
+					match{round: r, opening: o, north: p[0], south: p[1]},
+					match{round: r, opening: o, north: p[1], south: p[0]})
+			}
+		}
+	}
+	for i := range ms {
+		ms[i].num = i + 1
+	}
+	return ms
+}
+
+// check makes sure that every contestant can be started.
+func (t *tournament) check() error {
+	if len(t.players) < 2 {
+		return fmt.Errorf("a tournament needs at least two players")
+	}
+	for _, c := range t.players {
+		if c.command != "" {
+			continue
+		}
+		s, err := c.seat(t.config, 0, t.repetition)
+		if err != nil {
+			return err
+		}
+		s.Close()
+	}
+	return nil
+}
+
+// run plays the tournament, reporting each game as it finishes,
+// and then prints the standings.
+func (t *tournament) run(w io.Writer) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.records != "" {
+		if err := os.MkdirAll(t.records, 0777); err != nil {
+			return err
+		}
+	}
+	ms := t.matches()
+	// At most one game per worker is waiting or being played,
+	// so that the tournament can stop early.
+	jobs := make(chan match, t.parallel)
+	results := make(chan outcome)
+	for i := 0; i < t.parallel; i++ {
+		go func() {
+			for m := range jobs {
+				results <- t.play(m)
+			}
+		}()
+	}
+	defer close(jobs)
+	next, running := 0, 0
+	for ; next < len(ms) && running < t.parallel; next++ {
+		jobs <- ms[next]
+		running++
+	}
+
+	var outcomes []outcome
+	var first homeworlds.MatchResult // the first player's results, for the SPRT
+	stopped := false
+	for running > 0 {
+		o := <-results
+		running--
+		if o.err != nil {
+			return o.err
+		}
+		fmt.Fprintf(w, "Game %d/%d: %s vs %s, opening %d: %s (%s)",
+			o.num, len(ms), t.players[o.north].name, t.players[o.south].name,
+			o.opening+1, o.result(), o.reason)
+		outcomes = append(outcomes, o)
+		if t.sprt != nil {
+			if o.north == 0 {
+				addScore(&first, o.score)
+			} else {
+				addScore(&first, 1-o.score)
+			}
+			fmt.Fprintf(w, " LLR %.2f", t.sprt.LLR(first))
+			if !stopped && t.sprt.Test(first) != homeworlds.SPRTContinue {
+				stopped = true
+			}
+		}
+		fmt.Fprintln(w)
+		if !stopped && next < len(ms) {
+			jobs <- ms[next]
+			next++
+			running++
+		}
+	}
+	fmt.Fprintln(w)
+	t.printStandings(w, outcomes)
+	if t.sprt != nil {
+		lower, upper := t.sprt.Bounds()
+		fmt.Fprintf(w, "\nSPRT: elo0=%g elo1=%g alpha=%g beta=%g\n",
+			t.sprt.Elo0, t.sprt.Elo1, t.sprt.Alpha, t.sprt.Beta)
+		fmt.Fprintf(w, "LLR %.2f [%.2f, %.2f]: %s after %d games\n",
+			t.sprt.LLR(first), lower, upper, t.sprt.Test(first), first.Games())
+	}
+	return nil
+}
+
+// play plays one game of the tournament.
+// A player whose seat fails to move loses the game.
+// An error is returned only if something else goes wrong.
+func (t *tournament) play(m match) outcome {
+	o := outcome{match: m}
+	var g *homeworlds.Game
+	if s := t.openings[m.opening]; s == "startpos" {
+		g = homeworlds.NewGame(2)
+	} else {
+		var err error
+		if g, err = homeworlds.ParseFEN(s); err != nil {
+			o.err = err
+			return o
+		}
+	}
+	g.Repetition = t.repetition
+
+	var seats [2]seat
+	for i, p := range []int{m.north, m.south} {
+		s, err := t.players[p].seat(t.config, m.num, t.repetition)
+		if err != nil {
+			o.err = err
+			return o
+		}
+		defer s.Close()
+		seats[i] = s
+	}
+	for !g.IsOver() && g.Ply() < t.maxTurns {
+		pl := g.CurrentPlayer
+		turn, err := seats[pl].think(g)
+		if err == nil {
+			err = g.Play(turn)
+		}
+		if err != nil {
+			o.score = 1
+			if pl == homeworlds.North {
+				o.score = 0
+			}
+			o.reason = fmt.Sprintf("%s forfeits: %v", pl, err)
+			return o.saved(t, g)
+		}
+	}
+	switch {
+	case g.IsDraw():
+		o.score, o.reason = 0.5, "draw by repetition"
+	case !g.IsOver():
+		o.score, o.reason = 0.5, fmt.Sprintf("drawn after %d turns", g.Ply())
+	case g.Winner() == homeworlds.North:
+		o.score, o.reason = 1, "North wins"
+	default:
+		o.score, o.reason = 0, "South wins"
+	}
+	return o.saved(t, g)
+}
+
+// saved writes the game's record, if the tournament keeps records,
+// and returns the outcome.
+func (o outcome) saved(t *tournament, g *homeworlds.Game) outcome {
+	if t.records == "" {
+		return o
+	}
+	rec, err := g.Record()
+	if err != nil {
+		o.err = err
+		return o
+	}
+	if rec.Headers == nil {
+		rec.Headers = make(map[string]string)
+	}
+	rec.Headers["North"] = t.players[o.north].name
+	rec.Headers["South"] = t.players[o.south].name
+	rec.Headers["Round"] = fmt.Sprint(o.round)
+	rec.Headers["Opening"] = fmt.Sprint(o.opening + 1)
+	rec.Headers["Result"] = o.result()
+	rec.Headers["Termination"] = o.reason
+	f, err := os.Create(filepath.Join(t.records, fmt.Sprintf("game%04d.txt", o.num)))
+	if err != nil {
+		o.err = err
+		return o
+	}
+	if err := homeworlds.WriteRecord(f, rec); err != nil {
+		f.Close()
+		o.err = err
+		return o
+	}
+	o.err = f.Close()
+	return o
+}
+
+// addScore counts a game with the given score in r.
+func addScore(r *homeworlds.MatchResult, score float64) {
+	switch score {
+	case 1:
+		r.Wins++
+	case 0:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+}
+
+// fmtElo formats a difference in Elo rating and its margin of error.
+func fmtElo(r homeworlds.MatchResult) string {
+	diff, margin := r.Elo()
+	if math.IsInf(diff, 0) || math.IsInf(margin, 0) || math.IsNaN(margin) {
+		return fmt.Sprintf("%+.0f", diff)
+	}
+	return fmt.Sprintf("%+.0f ± %.0f", diff, margin)
+}
+
+// printStandings prints a table of each player's results, best first,
+// followed by the results of each pairing.
+// A player's Elo is their rating relative to the opponents they faced.
+func (t *tournament) printStandings(w io.Writer, outcomes []outcome) {
+	totals := make([]homeworlds.MatchResult, len(t.players))
+	pairs := make(map[[2]int]*homeworlds.MatchResult)
+	for _, o := range outcomes {
+		addScore(&totals[o.north], o.score)
+		addScore(&totals[o.south], 1-o.score)
+		// Pairings are counted from the point of view
+		// of the player listed first.
+		a, b, score := o.north, o.south, o.score
+		if a > b {
+			a, b, score = b, a, 1-score
+		}
+		if pairs[[2]int{a, b}] == nil {
+			pairs[[2]int{a, b}] = new(homeworlds.MatchResult)
+		}
+		addScore(pairs[[2]int{a, b}], score)
+	}
+
+	order := make([]int, len(t.players))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return totals[order[i]].Points() > totals[order[j]].Points()
+	})
+	width := len("Player")
+	for _, c := range t.players {
+		if len(c.name) > width {
+			width = len(c.name)
+		}
+	}
+	fmt.Fprintf(w, "%-*s %5s %4s %5s %4s %6s %6s  %s\n", width, "Player", "Games", "Won", "Drawn", "Lost", "Points", "Score", "Elo")
+	for _, i := range order {
+		r := totals[i]
+		if r.Games() == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%-*s %5d %4d %5d %4d %6.1f %5.1f%%  %s\n",
+			width, t.players[i].name, r.Games(), r.Wins, r.Draws, r.Losses, r.Points(), 100*r.Score(), fmtElo(r))
+	}
+
+	fmt.Fprintln(w)
+	for i := range t.players {
+		for j := i + 1; j < len(t.players); j++ {
+			r := pairs[[2]int{i, j}]
+			if r == nil {
+				continue
+			}
+			fmt.Fprintf(w, "%s vs %s: %s (%.1f%%), Elo %s\n",
+				t.players[i].name, t.players[j].name, r, 100*r.Score(), fmtElo(*r))
+		}
+	}
+}
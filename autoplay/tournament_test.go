@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/magical/homeworlds"
+)
+
+func TestTournament(t *testing.T) {
+	var players contestants
+	for _, s := range []string{"alpha=depth=1,qdepth=0", "beta=engine=random"} {
+		if err := players.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr := &tournament{
+		players:  players,
+		openings: []string{"startpos", newGame().FEN()},
+		rounds:   1,
+		parallel: 2,
+		maxTurns: 40,
+		config:   homeworlds.DefaultAIConfig(),
+	}
+
+	// Each opening is played once with each color.
+	ms := tr.matches()
+	if len(ms) != 4 {
+		t.Fatalf("got %d matches, want 4", len(ms))
+	}
+	seen := make(map[[2]int]bool)
+	for _, m := range ms {
+		seen[[2]int{m.opening, m.north}] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("matches %v don't give each player both colors in each opening", ms)
+	}
+
+	var buf bytes.Buffer
+	if err := tr.run(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	lines := strings.Split(out, "\n")
+	games := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Game ") {
+			games++
+		}
+	}
+	if games != 4 {
+		t.Errorf("reported %d games, want 4:\n%s", games, out)
+	}
+	var w, d, l int
+	i := strings.Index(out, "alpha vs beta: ")
+	if i < 0 {
+		t.Fatalf("no result for alpha vs beta:\n%s", out)
+	}
+	if _, err := fmt.Sscanf(out[i:], "alpha vs beta: +%d =%d -%d", &w, &d, &l); err != nil || w+d+l != 4 {
+		t.Errorf("alpha vs beta: got +%d =%d -%d (%v), want four games:\n%s", w, d, l, err, out)
+	}
+	rows := 0
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) < 5 || (f[0] != "alpha" && f[0] != "beta") || f[1] == "vs" {
+			continue
+		}
+		want := fmt.Sprintf("%d %d %d %d", w+d+l, w, d, l)
+		if f[0] == "beta" {
+			want = fmt.Sprintf("%d %d %d %d", w+d+l, l, d, w)
+		}
+		if got := strings.Join(f[1:5], " "); got != want {
+			t.Errorf("standings for %s: got games, won, drawn, lost %s, want %s", f[0], got, want)
+		}
+		rows++
+	}
+	if rows != 2 {
+		t.Errorf("standings have %d rows, want 2:\n%s", rows, out)
+	}
+}
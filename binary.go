@@ -0,0 +1,52 @@
+package homeworlds
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MarshalBinary encodes the position as a canonical byte string.
+//
+// The encoding is one byte for the player to move,
+// three bytes for the bank (two bits per piece, R1 in the low bits),
+// and nine bytes for each star: three for its pieces,
+// three for North's ships and three for South's ships.
+// The homeworlds come first, North's then South's,
+// and the other stars are sorted,
+// so positions which differ only in the order of their stars
+// have the same encoding.
+func (pos Position) MarshalBinary() ([]byte, error) {
+	return pos.appendKey(make([]byte, 0, 4+9*len(pos.stars))), nil
+}
+
+// UnmarshalBinary decodes a position encoded by MarshalBinary.
+func (pos *Position) UnmarshalBinary(b []byte) error {
+	if len(b) < 4+9*2 || (len(b)-4)%9 != 0 {
+		return fmt.Errorf("homeworlds: invalid position length %d", len(b))
+	}
+	if b[0] > uint8(South) {
+		return fmt.Errorf("homeworlds: invalid player %d", b[0])
+	}
+	var p Position
+	p.player = b[0]
+	p.bank = bankFromBytes(b[1:4])
+	p.stars = make([]Dwarf, (len(b)-4)/9)
+	for i := range p.stars {
+		s := b[4+9*i:]
+		p.stars[i].pieces = bankFromBytes(s[0:3])
+		p.stars[i].ships[North] = bankFromBytes(s[3:6])
+		p.stars[i].ships[South] = bankFromBytes(s[6:9])
+		if i >= 2 && p.stars[i].pieces.IsEmpty() {
+			return errors.New("homeworlds: star has no pieces")
+		}
+	}
+	if err := p.Check(); err != nil {
+		return fmt.Errorf("homeworlds: %v", err)
+	}
+	*pos = p
+	return nil
+}
+
+func bankFromBytes(b []byte) Bank {
+	return Bank{uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16}
+}
@@ -0,0 +1,40 @@
+package homeworlds
+
+import "testing"
+
+func TestMarshalBinary(t *testing.T) {
+	pos := PositionFromGame(game)
+	b, err := pos.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 4+9*5 {
+		t.Errorf("encoding has %d bytes, want %d", len(b), 4+9*5)
+	}
+
+	// Reversing the order of the other stars doesn't change the encoding.
+	rev := pos.copy()
+	for i, j := 2, len(rev.stars)-1; i < j; i, j = i+1, j-1 {
+		rev.stars[i], rev.stars[j] = rev.stars[j], rev.stars[i]
+	}
+	c, _ := rev.MarshalBinary()
+	if string(b) != string(c) {
+		t.Errorf("encoding depends on the order of stars:\n%x\n%x", b, c)
+	}
+
+	var q Position
+	if err := q.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if q.key() != pos.key() {
+		t.Errorf("round trip changed the position: got %s, want %s", q.FEN(), pos.FEN())
+	}
+
+	b[1] ^= 1 // one R1 too few in the bank
+	if err := q.UnmarshalBinary(b); err == nil {
+		t.Errorf("UnmarshalBinary accepted an inconsistent bank")
+	}
+	if err := q.UnmarshalBinary(b[:10]); err == nil {
+		t.Errorf("UnmarshalBinary accepted a short encoding")
+	}
+}
@@ -0,0 +1,200 @@
+package homeworlds
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AIConfig holds the settings of an AI.
+type AIConfig struct {
+	// Depth is the number of plies to search.
+	Depth int
+
+	// QuiescenceDepth limits how many plies past Depth
+	// the search follows attacks, catastrophes and threats.
+	QuiescenceDepth int
+
+	// TimeLimit, if non-zero, limits the time spent choosing an action.
+	// The search deepens one ply at a time until it reaches Depth
+	// or runs out of time.
+	TimeLimit time.Duration
+
+	// Seed seeds the random number generator
+	// used to shuffle actions.
+	Seed int64
+
+	// Trace logs each action searched up to the given ply.
+	Trace int
+
+	// Debug enables sanity checks of every position searched.
+	Debug bool
+
+	// Engine is the name of the search algorithm;
+	// see Engines.
+	Engine string
+
+	// Evaluator is the name of the function used to score positions;
+	// see Evaluators.
+	Evaluator string
+}
+
+// DefaultAIConfig returns the configuration used by NewAI.
+func DefaultAIConfig() AIConfig {
+	return AIConfig{
+		Depth:           3,
+		QuiescenceDepth: 4,
+		Seed:            1,
+		Engine:          "minimax",
+		Evaluator:       "default",
+	}
+}
+
+// An Engine chooses an action for the current player.
+type Engine func(ai *AI, pos Position, last BasicAction) (Action, float64)
+
+// An Evaluator scores a position from the point of view of the current player.
+// Scores range from -1 (lost) to 1 (won).
+type Evaluator func(pos Position) float64
+
+// Engines maps engine names to engines.
+var Engines = map[string]Engine{
+	"minimax": (*AI).Minimax,
+	"random":  (*AI).Random,
+}
+
+// Evaluators maps evaluator names to evaluators.
+var Evaluators = map[string]Evaluator{
+	"default":  Position.score,
+	"material": Position.materialScore,
+}
+
+// Set sets the named option to the given value.
+// The option names are the same as the flags added by AddFlags.
+// If the value is not valid, the configuration is unchanged.
+func (c *AIConfig) Set(name, value string) error {
+	n := *c
+	var err error
+	switch name {
+	case "depth":
+		n.Depth, err = strconv.Atoi(value)
+		if err == nil && n.Depth < 1 {
+			err = errors.New("must be at least 1")
+		}
+	case "qdepth":
+		n.QuiescenceDepth, err = strconv.Atoi(value)
+	case "time":
+		n.TimeLimit, err = time.ParseDuration(value)
+	case "seed":
+		n.Seed, err = strconv.ParseInt(value, 10, 64)
+	case "trace":
+		n.Trace, err = strconv.Atoi(value)
+	case "debug":
+		n.Debug, err = strconv.ParseBool(value)
+	case "engine":
+		if _, ok := Engines[value]; !ok {
+			return fmt.Errorf("unknown engine %q (have %s)", value, engineNames())
+		}
+		n.Engine = value
+	case "eval":
+		if _, ok := Evaluators[value]; !ok {
+			return fmt.Errorf("unknown evaluator %q (have %s)", value, evaluatorNames())
+		}
+		n.Evaluator = value
+	default:
+		return fmt.Errorf("unknown AI option %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("AI option %s: %v", name, err)
+	}
+	*c = n
+	return nil
+}
+
+// String formats the configuration as a comma-separated list
+// of name=value pairs, in the form accepted by ParseAIConfig.
+func (c AIConfig) String() string {
+	return fmt.Sprintf("depth=%d,qdepth=%d,time=%s,seed=%d,trace=%d,debug=%t,engine=%s,eval=%s",
+		c.Depth, c.QuiescenceDepth, c.TimeLimit, c.Seed, c.Trace, c.Debug, c.Engine, c.Evaluator)
+}
+
+// ParseAIConfig parses a comma-separated list of name=value pairs
+// and applies them to the default configuration.
+// An empty string results in the default configuration.
+func ParseAIConfig(s string) (AIConfig, error) {
+	return ParseAIOptions(DefaultAIConfig(), s)
+}
+
+// ParseAIOptions is like ParseAIConfig,
+// but applies the settings to c instead of the default configuration.
+func ParseAIOptions(c AIConfig, s string) (AIConfig, error) {
+	if s == "" {
+		return c, nil
+	}
+	for _, opt := range strings.Split(s, ",") {
+		i := strings.Index(opt, "=")
+		if i < 0 {
+			return c, fmt.Errorf("AI option %q: missing value", opt)
+		}
+		if err := c.Set(opt[:i], opt[i+1:]); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// AddFlags defines command-line flags for each setting in the flag set.
+// The flags default to the current values of c.
+func (c *AIConfig) AddFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Depth, "depth", c.Depth, "AI search `depth`")
+	fs.IntVar(&c.QuiescenceDepth, "qdepth", c.QuiescenceDepth, "AI quiescence search `depth`")
+	fs.DurationVar(&c.TimeLimit, "time", c.TimeLimit, "AI time limit per move (0 for none)")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "AI random `seed`")
+	fs.IntVar(&c.Trace, "trace", c.Trace, "trace AI search up to `ply`")
+	fs.BoolVar(&c.Debug, "debug", c.Debug, "enable AI sanity checks")
+	fs.Var(option{c, "engine"}, "engine", "AI search `engine` ("+engineNames()+")")
+	fs.Var(option{c, "eval"}, "eval", "AI `evaluator` ("+evaluatorNames()+")")
+}
+
+// option adapts an AIConfig setting to the flag.Value interface.
+type option struct {
+	c    *AIConfig
+	name string
+}
+
+func (o option) Set(value string) error { return o.c.Set(o.name, value) }
+
+func (o option) String() string {
+	if o.c == nil {
+		return ""
+	}
+	switch o.name {
+	case "engine":
+		return o.c.Engine
+	case "eval":
+		return o.c.Evaluator
+	}
+	return ""
+}
+
+func engineNames() string {
+	var s []string
+	for name := range Engines {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ", ")
+}
+
+func evaluatorNames() string {
+	var s []string
+	for name := range Evaluators {
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ", ")
+}
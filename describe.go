@@ -0,0 +1,223 @@
+package homeworlds
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StarNames returns the names of the game's stars
+// in the order of the stars of PositionFromGame(g),
+// so that an index in a Position can be mapped to a name.
+// The name of a missing homeworld is empty.
+func (g *Game) StarNames() []string {
+	names := make([]string, 2, 2+len(g.Stars))
+	for pl := North; pl <= South; pl++ {
+		if g.Homeworld(pl) != nil {
+			names[pl] = g.Homeworlds[pl]
+		}
+	}
+	for _, name := range g.sortedStars() {
+		if !g.Stars[name].IsHomeworld {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// actionTurn translates an action for pos into a turn.
+// Names holds the names of the stars of pos,
+// and newName is called to name each discovered star.
+// Catastrophes, which a Position carries out automatically,
+// become explicit catastrophe commands.
+func actionTurn(pos Position, names []string, a Action, newName func() string) (Turn, error) {
+	names = append([]string(nil), names...)
+	acts := []BasicAction{a.Basic()}
+	if a.Type() == Sacrifice {
+		for i := 0; i < a.N(); i++ {
+			acts = append(acts, a.Action(i))
+		}
+	}
+	var t Turn
+	for _, b := range acts {
+		if b.Type() == Pass {
+			t = append(t, Command{Type: Pass})
+			continue
+		}
+		if b.System() >= len(names) || names[b.System()] == "" {
+			return nil, fmt.Errorf("no such system %d", b.System())
+		}
+		c := Command{Type: b.Type(), Ship: b.Ship(), System: names[b.System()]}
+		switch b.Type() {
+		case Build, Attack, Sacrifice:
+		case Trade:
+			c.NewShip = b.NewShip()
+		case Discover:
+			c.NewShip = b.NewSystem()
+			c.NewSystem = newName()
+		case Move:
+			if b.ToSystem() >= len(names) || names[b.ToSystem()] == "" {
+				return nil, fmt.Errorf("no such system %d", b.ToSystem())
+			}
+			c.NewSystem = names[b.ToSystem()]
+		default:
+			return nil, fmt.Errorf("cannot translate %s action", b.Type())
+		}
+		t = append(t, c)
+		if id, color, ok := pos.catastrophe(b); ok {
+			t = append(t, Command{Type: Catastrope, Color: color, System: names[id]})
+		}
+		next := pos.do(b)
+		names = renumber(pos, next, b, names, c.NewSystem)
+		pos = next
+	}
+	return t, nil
+}
+
+// renumber updates names, the names of the stars of pos,
+// to match the stars of next, the result of doing b.
+func renumber(pos, next Position, b BasicAction, names []string, newName string) []string {
+	if b.Type() == Discover {
+		names = append(names, newName)
+	}
+	removed := len(names) - len(next.stars)
+	if removed <= 0 {
+		return names
+	}
+	s := b.System()
+	if b.Type() != Move {
+		// only the system where the action took place can disappear
+		return deleteName(names, s)
+	}
+	r := b.ToSystem()
+	if removed == 2 {
+		if s > r {
+			return deleteName(deleteName(names, s), r)
+		}
+		return deleteName(deleteName(names, r), s)
+	}
+	// Either the ship left s empty,
+	// or a catastrophe destroyed r.
+	ships := len(pos.stars[s].ships[North].pieces()) + len(pos.stars[s].ships[South].pieces())
+	if s >= 2 && ships == 1 {
+		return deleteName(names, s)
+	}
+	return deleteName(names, r)
+}
+
+func deleteName(names []string, i int) []string {
+	return append(names[:i], names[i+1:]...)
+}
+
+// ActionTurn translates an action for PositionFromGame(g) into a turn,
+// naming any newly discovered stars.
+func (g *Game) ActionTurn(a Action) (Turn, error) {
+	return actionTurn(PositionFromGame(g), g.StarNames(), a, g.newStarName())
+}
+
+// FormatAction formats an action for PositionFromGame(g)
+// using the names of the game's stars.
+//
+// The output looks like this:
+//
+//	build B1 at North's homeworld
+//	move G1 from Orion to Virgo
+//	sacrifice Y2 at Orion; move G1 from Orion to South's homeworld; catastrophe green at South's homeworld
+func (g *Game) FormatAction(a Action) string {
+	t, err := g.ActionTurn(a)
+	if err != nil {
+		return a.Basic().String() + " [" + err.Error() + "]"
+	}
+	return g.FormatTurn(t)
+}
+
+// DescribeAction describes an action for PositionFromGame(g)
+// as an English sentence.
+//
+// The output looks like this:
+//
+//	North builds a small blue ship at their homeworld.
+//	South sacrifices a medium yellow ship at Orion and moves a small green ship from Orion to North's homeworld.
+func (g *Game) DescribeAction(a Action) string {
+	t, err := g.ActionTurn(a)
+	if err != nil {
+		return a.Basic().String() + " [" + err.Error() + "]"
+	}
+	return g.DescribeTurn(t)
+}
+
+// FormatTurn formats a turn, which is about to be played,
+// in the short form of FormatAction.
+func (g *Game) FormatTurn(t Turn) string {
+	parts := make([]string, len(t))
+	for i, c := range t {
+		parts[i] = g.fmtCommand(c, false)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DescribeTurn describes a turn, which is about to be played,
+// in the long form of DescribeAction.
+func (g *Game) DescribeTurn(t Turn) string {
+	parts := make([]string, len(t))
+	for i, c := range t {
+		parts[i] = g.fmtCommand(c, true)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "passes")
+	}
+	return fmt.Sprintf("%s %s.", g.CurrentPlayer, joinComma(parts))
+}
+
+func (g *Game) fmtCommand(c Command, long bool) string {
+	ship := Piece.String
+	if long {
+		ship = func(p Piece) string { return fmtShips([]Piece{p}) }
+	}
+	// verb picks the short or long form of a verb.
+	verb := func(short, long3 string) string {
+		if long {
+			return long3
+		}
+		return short
+	}
+	at := g.fmtSystem(c.System, long)
+	switch c.Type {
+	case Pass:
+		return verb("pass", "passes")
+	case Homeworld:
+		return fmt.Sprintf("%s %s, a %s star, with %s", verb("homeworld", "establishes"), c.System, fmtStar(c.Star[:]), ship(c.Ship))
+	case Discover:
+		return fmt.Sprintf("%s %s, a %s star, with %s from %s", verb("discover", "discovers"), c.NewSystem, c.NewShip, ship(c.Ship), at)
+	case Move:
+		return fmt.Sprintf("%s %s from %s to %s", verb("move", "moves"), ship(c.Ship), at, g.fmtSystem(c.NewSystem, long))
+	case Build:
+		return fmt.Sprintf("%s %s at %s", verb("build", "builds"), ship(c.Ship), at)
+	case Trade:
+		return fmt.Sprintf("%s %s for %s at %s", verb("trade", "trades"), ship(c.Ship), ship(c.NewShip), at)
+	case Attack:
+		return fmt.Sprintf("%s %s at %s", verb("attack", "captures"), ship(c.Ship), at)
+	case Sacrifice:
+		return fmt.Sprintf("%s %s at %s", verb("sacrifice", "sacrifices"), ship(c.Ship), at)
+	case Catastrope:
+		color := strings.ToLower(c.Color.String())
+		if long {
+			return fmt.Sprintf("triggers a %s catastrophe at %s", color, at)
+		}
+		return fmt.Sprintf("catastrophe %s at %s", color, at)
+	}
+	return c.String()
+}
+
+// fmtSystem formats the name of a system,
+// calling homeworlds by their owner.
+func (g *Game) fmtSystem(name string, long bool) string {
+	for pl := North; pl <= South; pl++ {
+		if hw, ok := g.Homeworlds[pl]; ok && hw == name || name == "home" && pl == g.CurrentPlayer {
+			if long && pl == g.CurrentPlayer {
+				return "their homeworld"
+			}
+			return pl.String() + "'s homeworld"
+		}
+	}
+	return name
+}
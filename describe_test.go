@@ -0,0 +1,47 @@
+package homeworlds
+
+import "testing"
+
+func TestFormatAction(t *testing.T) {
+	g, err := ParseFEN("223/222/231/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=R1:Y2:- beta=R2:G1:- N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := mksacrifice(Y2, 2)
+	a = a.append(mkmove(G1, 2, 0))
+	a = a.append(mkbasic(Discover, B3, 0, R2))
+
+	short := "sacrifice Y2 at alpha; move G1 from beta to North's homeworld; discover Achernar, a R2 star, with B3 from North's homeworld"
+	if got := g.FormatAction(a); got != short {
+		t.Errorf("FormatAction:\ngot  %s\nwant %s", got, short)
+	}
+	long := "North sacrifices a medium yellow ship at alpha, moves a small green ship from beta to their homeworld, and discovers Achernar, a R2 star, with a large blue ship from their homeworld."
+	if got := g.DescribeAction(a); got != long {
+		t.Errorf("DescribeAction:\ngot  %s\nwant %s", got, long)
+	}
+
+	turn, err := g.ActionTurn(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PositionFromGame(g)
+	want = want.do(a.Basic()).do(a.Action(0)).do(a.Action(1))
+	want.endturn()
+	if err := g.Play(turn); err != nil {
+		t.Fatalf("%s: %v", turn, err)
+	}
+	if got := PositionFromGame(g); !got.Equal(want) {
+		t.Errorf("playing %s gave %s, want %s", turn, got.FEN(), want.FEN())
+	}
+}
+
+func TestGameFromPosition(t *testing.T) {
+	pos := PositionFromGame(game)
+	g := GameFromPosition(pos)
+	if got := PositionFromGame(g); !got.Equal(pos) {
+		t.Errorf("got %s, want %s", got.FEN(), pos.FEN())
+	}
+	if got, want := g.FEN(), "333/121/000/222 North@N=Y3B1:G2G3:- South@S=Y1B2:-:G1G3 Achernar=B3:-:Y2G1G2G2 Aldebaran=Y3:G1:- Altair=G3:Y1:- N"; got != want {
+		t.Errorf("FEN() = %s, want %s", got, want)
+	}
+}
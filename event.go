@@ -0,0 +1,107 @@
+package homeworlds
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// An EventType identifies the kind of an Event.
+type EventType int
+
+const (
+	// SearchStart is sent when the AI begins searching the basic actions
+	// at the root of the game tree. Count is the number of actions.
+	SearchStart EventType = iota
+
+	// SearchSacrifices is sent when the AI begins searching the sacrifice actions
+	// at the root of the game tree. Count is the number of actions.
+	SearchSacrifices
+
+	// SearchAction is sent after an action has been searched,
+	// for plies up to the AI's trace setting.
+	SearchAction
+
+	// SearchDone is sent when the search is finished.
+	// Action and Score are the chosen action and its score.
+	SearchDone
+
+	// RepeatedPosition is sent when an action is skipped
+	// because it returns to an earlier position.
+	RepeatedPosition
+
+	// SanityFailure is sent when a position fails a sanity check.
+	// Err describes the problem.
+	SanityFailure
+
+	// DepthDone is sent when a search with a time limit
+	// finishes searching to a depth.
+	// Action and Score are the best action at that depth and its score.
+	DepthDone
+)
+
+// An Event describes the progress of a search.
+// Fields which don't apply to the event's type are left zero.
+type Event struct {
+	Type   EventType
+	Player Player
+	Ply    int
+	Depth  int
+	Action Action
+	Score  float64
+
+	// Min and Max are the bounds of the search window.
+	Min, Max float64
+
+	Count     int
+	Visited   int64
+	Evaluated int64
+	Elapsed   time.Duration
+
+	Err error
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case SearchStart:
+		return fmt.Sprintf("%d basic actions to examine", e.Count)
+	case SearchSacrifices:
+		return fmt.Sprintf("%d sacrifice actions to examine", e.Count)
+	case SearchAction:
+		return fmt.Sprintf("%*s player=%d ply=%d depth=%d v=%f min=%f max=%f move=%s",
+			e.Ply, "", e.Player, e.Ply, e.Depth, e.Score, e.Min, e.Max, e.Action)
+	case SearchDone:
+		ms := float64(e.Elapsed) / float64(time.Millisecond)
+		return fmt.Sprintf("visited=%d (%.1f/ms) evaluated=%d (%.1f/ms) in %s",
+			e.Visited, float64(e.Visited)/ms,
+			e.Evaluated, float64(e.Evaluated)/ms,
+			e.Elapsed)
+	case RepeatedPosition:
+		return fmt.Sprintf("action returns to an earlier state: %s", e.Action)
+	case SanityFailure:
+		return fmt.Sprintf("sanity check failed after %s: %v", e.Action, e.Err)
+	case DepthDone:
+		return fmt.Sprintf("depth %d: %s v=%f visited=%d in %s", e.Depth, e.Action, e.Score, e.Visited, e.Elapsed)
+	}
+	return fmt.Sprintf("unknown event %d", e.Type)
+}
+
+// A Logger receives events from an AI.
+type Logger interface {
+	Log(e Event)
+}
+
+// LoggerFunc adapts an ordinary function to the Logger interface.
+type LoggerFunc func(e Event)
+
+func (f LoggerFunc) Log(e Event) { f(e) }
+
+// NewTextLogger returns a Logger which writes each event
+// to w as a line of text prefixed with the time.
+func NewTextLogger(w io.Writer) Logger {
+	l := log.New(w, "", log.LstdFlags)
+	return LoggerFunc(func(e Event) {
+		l.Print(e)
+	})
+}
@@ -0,0 +1,360 @@
+package homeworlds
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A position string is a compact, single-line description
+// of a position, in the spirit of chess's FEN.
+// It consists of space-separated fields:
+// the bank, one field for each star, and the player to move.
+//
+//	233/232/231/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=R1:G1:- N
+//
+// The bank lists how many small, medium and large pieces
+// of each color are in the bank, in the order red, yellow, green, blue.
+//
+// Each star is written as name=pieces:north:south,
+// where pieces are the pieces the star is made of,
+// and north and south are the ships each player has there.
+// Pieces are listed in the order R1, R2, ... B3; an empty list is written as "-".
+// The name of a homeworld is followed by @N or @S.
+// Names are optional; a Position's stars have no names.
+//
+// Homeworlds come first, North's then South's,
+// followed by the other stars sorted by name.
+
+// FEN returns the game's position string.
+func (g *Game) FEN() string {
+	var buf bytes.Buffer
+	var bank Bank
+	for p, n := range g.Bank {
+		bank.Set(p, n)
+	}
+	writeFENBank(&buf, bank)
+	for _, pl := range []Player{North, South} {
+		if s := g.Homeworld(pl); s != nil {
+			buf.WriteByte(' ')
+			writeFENStar(&buf, s.Name, true, pl, dwarfFromStar(s))
+		}
+	}
+	for _, name := range g.sortedStars() {
+		s := g.Stars[name]
+		if s.IsHomeworld {
+			continue
+		}
+		buf.WriteByte(' ')
+		writeFENStar(&buf, s.Name, false, 0, dwarfFromStar(s))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(fenPlayer(g.CurrentPlayer))
+	return buf.String()
+}
+
+// FEN returns the position string of pos.
+// The first two stars are written as North's and South's homeworlds.
+func (pos Position) FEN() string {
+	var buf bytes.Buffer
+	writeFENBank(&buf, pos.bank)
+	for i := range pos.stars {
+		buf.WriteByte(' ')
+		writeFENStar(&buf, "", i < 2, Player(i), pos.stars[i])
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(fenPlayer(pos.CurrentPlayer()))
+	return buf.String()
+}
+
+func fenPlayer(pl Player) string {
+	if pl == North {
+		return "N"
+	}
+	return "S"
+}
+
+func writeFENBank(buf *bytes.Buffer, b Bank) {
+	for p := R1; p <= B3; p++ {
+		if p > R1 && p.Size() == Small {
+			buf.WriteByte('/')
+		}
+		buf.WriteByte(byte('0' + b.Get(p)))
+	}
+}
+
+func writeFENStar(buf *bytes.Buffer, name string, homeworld bool, pl Player, s Dwarf) {
+	buf.WriteString(name)
+	if homeworld {
+		buf.WriteString("@" + fenPlayer(pl))
+	}
+	buf.WriteByte('=')
+	writeFENPieces(buf, s.pieces)
+	buf.WriteByte(':')
+	writeFENPieces(buf, s.ships[North])
+	buf.WriteByte(':')
+	writeFENPieces(buf, s.ships[South])
+}
+
+func writeFENPieces(buf *bytes.Buffer, b Bank) {
+	if b.IsEmpty() {
+		buf.WriteByte('-')
+		return
+	}
+	for it := b.Iter(); !it.Done(); it.Next() {
+		for i := 0; i < it.Count(); i++ {
+			buf.WriteString(it.Piece().String())
+		}
+	}
+}
+
+type fenStar struct {
+	name      string
+	homeworld bool
+	player    Player
+	star      Dwarf
+}
+
+type fenPosition struct {
+	bank   Bank
+	stars  []fenStar
+	player Player
+}
+
+func parseFEN(s string) (*fenPosition, error) {
+	f := strings.Fields(s)
+	if len(f) < 2 {
+		return nil, fmt.Errorf("position string has too few fields")
+	}
+	var pos fenPosition
+	var err error
+	if pos.bank, err = parseFENBank(f[0]); err != nil {
+		return nil, err
+	}
+	if err := pos.player.UnmarshalText([]byte(fenPlayerName(f[len(f)-1]))); err != nil {
+		return nil, fmt.Errorf("invalid player to move %q", f[len(f)-1])
+	}
+	var hw [2]bool
+	for _, field := range f[1 : len(f)-1] {
+		st, err := parseFENStar(field)
+		if err != nil {
+			return nil, err
+		}
+		if st.homeworld {
+			if hw[st.player] {
+				return nil, fmt.Errorf("%s has two homeworlds", st.player)
+			}
+			hw[st.player] = true
+		}
+		pos.stars = append(pos.stars, st)
+	}
+	return &pos, nil
+}
+
+func fenPlayerName(s string) string {
+	switch s {
+	case "N", "n":
+		return "north"
+	case "S", "s":
+		return "south"
+	}
+	return s
+}
+
+func parseFENBank(s string) (Bank, error) {
+	var b Bank
+	groups := strings.Split(s, "/")
+	if len(groups) != 4 {
+		return b, fmt.Errorf("invalid bank %q: want four colors", s)
+	}
+	for c, g := range groups {
+		if len(g) != 3 {
+			return b, fmt.Errorf("invalid bank %q: want three sizes of %s", s, Color(c))
+		}
+		for i := 0; i < 3; i++ {
+			n := g[i] - '0'
+			if n > 3 {
+				return b, fmt.Errorf("invalid bank %q: bad count %q", s, g[i])
+			}
+			b.Set(Piece(c*3+i), int(n))
+		}
+	}
+	return b, nil
+}
+
+func parseFENStar(s string) (fenStar, error) {
+	var st fenStar
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return st, fmt.Errorf("invalid star %q: missing '='", s)
+	}
+	st.name = s[:i]
+	if j := strings.LastIndex(st.name, "@"); j >= 0 {
+		switch strings.ToUpper(st.name[j+1:]) {
+		case "N":
+			st.player = North
+		case "S":
+			st.player = South
+		default:
+			return st, fmt.Errorf("invalid star %q: bad homeworld marker", s)
+		}
+		st.homeworld = true
+		st.name = st.name[:j]
+	}
+	f := strings.Split(s[i+1:], ":")
+	if len(f) != 3 {
+		return st, fmt.Errorf("invalid star %q: want pieces:north:south", s)
+	}
+	var err error
+	if st.star.pieces, err = parseFENPieces(f[0]); err != nil {
+		return st, fmt.Errorf("invalid star %q: %v", s, err)
+	}
+	for pl := North; pl <= South; pl++ {
+		if st.star.ships[pl], err = parseFENPieces(f[pl+1]); err != nil {
+			return st, fmt.Errorf("invalid star %q: %v", s, err)
+		}
+	}
+	return st, nil
+}
+
+func parseFENPieces(s string) (Bank, error) {
+	var b Bank
+	if s == "-" {
+		return b, nil
+	}
+	if s == "" || len(s)%2 != 0 {
+		return b, fmt.Errorf("bad piece list %q", s)
+	}
+	for i := 0; i < len(s); i += 2 {
+		p, err := ParsePiece(s[i : i+2])
+		if err != nil {
+			return b, err
+		}
+		if b.Get(p) == 3 {
+			return b, fmt.Errorf("too many %s", p)
+		}
+		b.Put(p)
+	}
+	return b, nil
+}
+
+func (b Bank) pieces() []Piece {
+	var l []Piece
+	for it := b.Iter(); !it.Done(); it.Next() {
+		for i := 0; i < it.Count(); i++ {
+			l = append(l, it.Piece())
+		}
+	}
+	return l
+}
+
+// ParseFEN parses a position string into a Game.
+// Every star except the homeworlds must be named;
+// an unnamed homeworld is named after its owner.
+// The game's history starts at the parsed position.
+func ParseFEN(s string) (*Game, error) {
+	fp, err := parseFEN(s)
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{
+		NumPlayers:    2,
+		CurrentPlayer: fp.player,
+		Bank:          make(map[Piece]int),
+		Homeworlds:    make(map[Player]string),
+		Stars:         make(map[string]*Star),
+	}
+	for p := R1; p <= B3; p++ {
+		g.Bank[p] = fp.bank.Get(p)
+	}
+	for _, st := range fp.stars {
+		name := st.name
+		if name == "" {
+			if !st.homeworld {
+				return nil, fmt.Errorf("star %s has no name", st.star.pieces)
+			}
+			name = st.player.String()
+		}
+		if _, dup := g.Stars[name]; dup {
+			return nil, fmt.Errorf("duplicate star %q", name)
+		}
+		if st.star.pieces.IsEmpty() {
+			return nil, fmt.Errorf("star %q has no pieces", name)
+		}
+		if !st.homeworld && st.star.ships[North].IsEmpty() && st.star.ships[South].IsEmpty() {
+			return nil, fmt.Errorf("star %q has no ships", name)
+		}
+		star := &Star{
+			Name:        name,
+			IsHomeworld: st.homeworld,
+			Pieces:      st.star.pieces.pieces(),
+			Ships:       make(map[Player][]Piece),
+		}
+		for pl := North; pl <= South; pl++ {
+			if ships := st.star.ships[pl].pieces(); ships != nil {
+				star.Ships[pl] = ships
+			}
+		}
+		g.Stars[name] = star
+		if st.homeworld {
+			g.Homeworlds[st.player] = name
+		}
+	}
+	if err := PositionFromGame(g).Check(); err != nil {
+		return nil, err
+	}
+	g.ClearHistory()
+	return g, nil
+}
+
+// ParsePosition parses a position string into a Position.
+// Names are ignored, and the other stars keep the order they are given in.
+// Both homeworlds must be present, but they may be empty.
+func ParsePosition(s string) (Position, error) {
+	var pos Position
+	fp, err := parseFEN(s)
+	if err != nil {
+		return pos, err
+	}
+	pos.bank = fp.bank
+	pos.player = uint8(fp.player)
+	pos.stars = make([]Dwarf, 2, 2+len(fp.stars))
+	var hw [2]bool
+	var rest []fenStar
+	for _, st := range fp.stars {
+		if st.homeworld {
+			pos.stars[st.player] = st.star
+			hw[st.player] = true
+		} else {
+			rest = append(rest, st)
+		}
+	}
+	if !hw[North] || !hw[South] {
+		return pos, fmt.Errorf("position needs both homeworlds")
+	}
+	for _, st := range rest {
+		if st.star.pieces.IsEmpty() {
+			return pos, fmt.Errorf("star has no pieces")
+		}
+		pos.stars = append(pos.stars, st.star)
+	}
+	if err := pos.Check(); err != nil {
+		return pos, err
+	}
+	return pos, nil
+}
+
+// MarshalText encodes the position as a position string.
+func (pos Position) MarshalText() ([]byte, error) {
+	return []byte(pos.FEN()), nil
+}
+
+// UnmarshalText decodes a position string, as ParsePosition does.
+func (pos *Position) UnmarshalText(b []byte) error {
+	p, err := ParsePosition(string(b))
+	if err != nil {
+		return err
+	}
+	*pos = p
+	return nil
+}
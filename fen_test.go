@@ -0,0 +1,49 @@
+package homeworlds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFEN(t *testing.T) {
+	want := "333/121/000/222 @N=Y3B1:G2G3:- @S=Y1B2:-:G1G3 grover=B3:-:Y2G1G2G2 orion=Y3:G1:- virgo=G3:Y1:- N"
+	g, err := ParseFEN(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.FEN(); got != strings.Replace(strings.Replace(want, "@N", "North@N", 1), "@S", "South@S", 1) {
+		t.Errorf("FEN() = %q", got)
+	}
+	pos := PositionFromGame(game)
+	if got := PositionFromGame(g); !got.Equal(pos) {
+		t.Errorf("parsed game has position %v, want %v", got, pos)
+	}
+	if got := pos.FEN(); got != "333/121/000/222 @N=Y3B1:G2G3:- @S=Y1B2:-:G1G3 =B3:-:Y2G1G2G2 =Y3:G1:- =G3:Y1:- N" {
+		t.Errorf("Position.FEN() = %q", got)
+	}
+	q, err := ParsePosition(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Equal(pos) {
+		t.Errorf("ParsePosition(%q) = %v, want %v", want, q, pos)
+	}
+}
+
+func TestFENErrors(t *testing.T) {
+	bad := []string{
+		"",
+		"333/333/333 @N=Y3B1:G3:- @S=Y1B2:-:G1 N",
+		"333/121/000/222 @N=Y3B1:G2G3:- @S=Y1B2:-:G1G3 grover=B3:-:Y2G1G2G2 orion=Y3:G1:- virgo=G3:Y1:- X",
+		"333/121/000/222 @N=Y3B1:G2G3:- @S=Y1B2:-:G1G3 =B3:-:Y2G1G2G2 orion=Y3:G1:- virgo=G3:Y1:- N",
+		"333/121/000/222 @N=Y3B1:G2G3:- @N=Y1B2:-:G1G3 grover=B3:-:Y2G1G2G2 orion=Y3:G1:- virgo=G3:Y1:- N",
+		"333/121/000/222 @N=Y3B1:G2G3:- @S=Y1B2:-:G1G3 grover=B3:-:Y2G1G2G2 orion=Y3:G1:- N",
+		"333/121/000/222 @N=Y3B1:G2G3 @S=Y1B2:-:G1G3 grover=B3:-:Y2G1G2G2 orion=Y3:G1:- virgo=G3:Y1:- N",
+		"333/121/000/222 @N=Y3B:G2G3:- @S=Y1B2:-:G1G3 grover=B3:-:Y2G1G2G2 orion=Y3:G1:- virgo=G3:Y1:- N",
+	}
+	for _, s := range bad {
+		if _, err := ParseFEN(s); err == nil {
+			t.Errorf("ParseFEN(%q) succeeded", s)
+		}
+	}
+}
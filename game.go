@@ -84,22 +84,40 @@ type Game struct {
 	// Stars is a map of star systems that are currently occupied.
 	// It is keyed by the name of the system.
 	Stars map[string]*Star
+
+	// Repetition decides what happens when a position recurs.
+	Repetition RepetitionPolicy
+
+	// Namer names the stars discovered by Apply.
+	// If it is nil, DefaultStarNames is used.
+	Namer StarNamer
+
+	// seen counts how many times each position has occurred
+	// at the end of a turn.
+	seen map[string]int
+
+	// history is the current state in the game's history,
+	// which links back to the start of the game,
+	// and undone holds the states of turns which were undone,
+	// the next one last.
+	history *state
+	undone  []*state
 }
 
 // Star represents an occupied star system.
 type Star struct {
 	// Name is the name given to the star system
 	// by the player who discovered it.
-	Name string
+	Name string `json:"name"`
 
 	// IsHomeworld records whether the star is a player's homeworld
-	IsHomeworld bool
+	IsHomeworld bool `json:"homeworld,omitempty"`
 
 	// The piece (or pieces) which the star is made of.
-	Pieces []Piece
+	Pieces []Piece `json:"pieces"`
 
 	// The ships occupying the star.
-	Ships map[Player][]Piece
+	Ships map[Player][]Piece `json:"ships"`
 }
 
 // Actions:
@@ -121,6 +139,7 @@ type Star struct {
 // TODO: Make sure we have access to the grow power.
 // Unless this is a sacrifice action...
 func (g *Game) Build(p Piece, s *Star) error {
+	g.begin()
 	if !s.ownsColor(g.CurrentPlayer, p.Color()) {
 		return errors.New("Build: color not available")
 	}
@@ -164,6 +183,7 @@ func (g *Game) take(p Piece) {
 // does not control the ship at the specified system,
 // or if the systems are not connected.
 func (g *Game) Move(p Piece, s, dst *Star) error {
+	g.begin()
 	if !s.connects(dst) {
 		return errors.New("Move: system not connected")
 	}
@@ -234,6 +254,7 @@ func (g *Game) put(p Piece) {
 // Returns an error if the target does not own the target piece,
 // or if the target piece is larger than the attacking player's largest ship.
 func (g *Game) Attack(p Piece, s *Star, target Player) error {
+	g.begin()
 	if target == g.CurrentPlayer {
 		return errors.New("Attack: cannot attack yourself")
 	}
@@ -272,6 +293,7 @@ func (s *Star) largest(pl Player) Size {
 // or if the desired piece is not available,
 // or if the player does not own the traded piece.
 func (g *Game) Trade(p Piece, s *Star, q Piece) error {
+	g.begin()
 	if p.Size() != q.Size() {
 		return errors.New("Trade: size mismatch")
 	}
@@ -293,12 +315,15 @@ func (g *Game) Trade(p Piece, s *Star, q Piece) error {
 // but this is not enforced.
 // Returns an error if the player does not own the piece.
 func (g *Game) Sacrifice(p Piece, s *Star) error {
+	g.begin()
 	ok := s.remove(g.CurrentPlayer, p)
 	if !ok {
 		return errors.New("Sacrifice: no such piece")
 	}
 	g.put(p)
-	// TODO: destroy star if empty
+	if s.empty() {
+		g.destroy(s)
+	}
 	return nil
 }
 
@@ -307,6 +332,7 @@ func (g *Game) Sacrifice(p Piece, s *Star) error {
 // This may result in the complete destruction of the system.
 // Returns an error if the color is not overpopulated.
 func (g *Game) Catastrophe(c Color, s *Star) error {
+	g.begin()
 	if s.population(c) < 4 {
 		return errors.New("Catastrophe: not overpopulated")
 	}
@@ -356,6 +382,7 @@ func (g *Game) filter(pieces []Piece, c Color) []Piece {
 // or if the active piece is not controlled by the player,
 // or if the name is already taken.
 func (g *Game) Discover(p Piece, s *Star, newPiece Piece, newName string) error {
+	g.begin()
 	if !s.owns(g.CurrentPlayer, p) {
 		return errors.New("Discover: no such piece")
 	}
@@ -401,22 +428,43 @@ func (g *Game) ResetBank() {
 	}
 }
 
+// EndTurn passes control to the next player
+// and records the new state in the game's history.
 func (g *Game) EndTurn() {
+	g.begin()
 	g.CurrentPlayer = Player((int(g.CurrentPlayer) + 1) % g.NumPlayers)
+	g.record(nil)
 }
 
 func (g *Game) IsOver() bool {
+	if g.IsDraw() {
+		return true
+	}
 	for pl := Player(0); int(pl) < g.NumPlayers; pl++ {
-		if len(g.Homeworld(pl).Ships[pl]) < 1 {
+		if g.lost(pl) {
 			return true
 		}
 	}
 	return false
 }
 
+// lost reports whether a player has no ships left at their homeworld,
+// or no homeworld left at all.
+// A player who has yet to build a homeworld has not lost.
+func (g *Game) lost(pl Player) bool {
+	if _, ok := g.Homeworlds[pl]; !ok {
+		return false
+	}
+	s := g.Homeworld(pl)
+	return s == nil || len(s.Ships[pl]) < 1
+}
+
 func (g *Game) Winner() Player {
+	if g.IsDraw() {
+		return Player(100)
+	}
 	for pl := Player(0); int(pl) < g.NumPlayers; pl++ {
-		if len(g.Homeworld(pl).Ships[pl]) > 0 {
+		if !g.lost(pl) {
 			return pl
 		}
 	}
@@ -429,20 +477,16 @@ func (g *Game) Homeworld(pl Player) *Star {
 }
 
 func (g0 *Game) Copy() *Game {
-	g := *g0
-	g.Bank = make(map[Piece]int)
-	g.Stars = make(map[string]*Star)
-	g.Homeworlds = make(map[Player]string)
-	for p, n := range g0.Bank {
-		g.Bank[p] = n
-	}
-	for k, s := range g0.Stars {
-		g.Stars[k] = s.Copy()
-	}
-	for pl, s := range g0.Homeworlds {
-		g.Homeworlds[pl] = s
+	g := g0.snapshot()
+	g.history = g0.history
+	g.undone = append([]*state(nil), g0.undone...)
+	if g0.seen != nil {
+		g.seen = make(map[string]int, len(g0.seen))
+		for k, n := range g0.seen {
+			g.seen[k] = n
+		}
 	}
-	return &g
+	return g
 }
 
 func (s0 *Star) Copy() *Star {
@@ -459,6 +503,7 @@ func (s0 *Star) Copy() *Star {
 
 // BuildHomeworld constructs a homeworld with the given pieces and initial ship.
 func (g *Game) BuildHomeworld(p1, p2, ship Piece, name string) error {
+	g.begin()
 	//XXX check phase?
 	if _, exists := g.Homeworlds[g.CurrentPlayer]; exists {
 		return errors.New("Homeworld: player already has a homeworld")
@@ -501,5 +546,6 @@ func NewGame(numPlayers int) *Game {
 	g.ResetBank()
 	g.Homeworlds = make(map[Player]string)
 	g.Stars = make(map[string]*Star)
+	g.ClearHistory()
 	return &g
 }
@@ -0,0 +1,195 @@
+package homeworlds
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A state is an entry in a game's history:
+// a snapshot of the game at the start or at the end of a turn.
+// States are never changed once they are recorded,
+// so copies of a game share the states they have in common.
+type state struct {
+	game *Game  // snapshot, without history
+	turn Turn   // turn which led to this state, if known
+	key  string // key of the position, for counting repetitions
+	prev *state // previous state, or nil at the start
+	ply  int
+}
+
+func newState(g *Game, t Turn, prev *state) *state {
+	s := &state{game: g.snapshot(), turn: t, key: PositionFromGame(g).key(), prev: prev}
+	if prev != nil {
+		s.ply = prev.ply + 1
+	}
+	return s
+}
+
+// ClearHistory forgets all previous turns
+// and makes the current state the start of the game's history.
+func (g *Game) ClearHistory() {
+	g.history = newState(g, nil, nil)
+	g.undone = nil
+	g.seen = map[string]int{g.history.key: 1}
+}
+
+// begin starts the game's history at the current state,
+// if it has none yet,
+// as for a game which was not made by NewGame.
+func (g *Game) begin() {
+	if g.history == nil {
+		g.ClearHistory()
+	}
+}
+
+// snapshot returns a copy of the game without its history.
+func (g0 *Game) snapshot() *Game {
+	g := *g0
+	g.Bank = make(map[Piece]int)
+	g.Stars = make(map[string]*Star)
+	g.Homeworlds = make(map[Player]string)
+	for p, n := range g0.Bank {
+		g.Bank[p] = n
+	}
+	for k, s := range g0.Stars {
+		g.Stars[k] = s.Copy()
+	}
+	for pl, s := range g0.Homeworlds {
+		g.Homeworlds[pl] = s
+	}
+	g.history = nil
+	g.undone = nil
+	g.seen = nil
+	return &g
+}
+
+// setState sets the state of the game to that of s,
+// which must not be used afterwards.
+func (g *Game) setState(s *Game) {
+	g.Phase = s.Phase
+	g.NumPlayers = s.NumPlayers
+	g.CurrentPlayer = s.CurrentPlayer
+	g.Bank = s.Bank
+	g.Homeworlds = s.Homeworlds
+	g.Stars = s.Stars
+}
+
+// positions returns the number of times each position has occurred.
+// A game without a history has only its current position.
+func (g *Game) positions() map[string]int {
+	if g.history == nil {
+		return map[string]int{PositionFromGame(g).key(): 1}
+	}
+	return g.seen
+}
+
+// record adds the current state to the history,
+// along with the turn which led to it, if known.
+// Any turns which were undone are discarded.
+func (g *Game) record(t Turn) {
+	if g.history == nil {
+		g.ClearHistory()
+		return
+	}
+	g.history = newState(g, t, g.history)
+	g.undone = nil
+	g.seen[g.history.key]++
+}
+
+// states returns the states from the start of the history
+// up to the current state.
+func (g *Game) states() []*state {
+	if g.history == nil {
+		return nil
+	}
+	list := make([]*state, g.history.ply+1)
+	for s := g.history; s != nil; s = s.prev {
+		list[s.ply] = s
+	}
+	return list
+}
+
+// Ply returns the number of turns taken since the start of the game's history,
+// not counting turns which have been undone.
+func (g *Game) Ply() int {
+	if g.history == nil {
+		return 0
+	}
+	return g.history.ply
+}
+
+// History returns a copy of the game's state at the start of its history
+// and at the end of every turn since.
+// If turns have been undone, they are included at the end;
+// the current state is at index g.Ply().
+func (g *Game) History() []*Game {
+	if g.history == nil {
+		return []*Game{g.snapshot()}
+	}
+	var games []*Game
+	for _, s := range g.states() {
+		games = append(games, s.game.snapshot())
+	}
+	for i := len(g.undone) - 1; i >= 0; i-- {
+		games = append(games, g.undone[i].game.snapshot())
+	}
+	return games
+}
+
+// Turns returns the turns taken since the start of the game's history,
+// including any which have been undone.
+// Turn i leads to state i+1 of History.
+// Turns which were ended with EndTurn rather than Play are nil.
+func (g *Game) Turns() []Turn {
+	var turns []Turn
+	for _, s := range g.states() {
+		if s.prev != nil {
+			turns = append(turns, s.turn)
+		}
+	}
+	for i := len(g.undone) - 1; i >= 0; i-- {
+		turns = append(turns, g.undone[i].turn)
+	}
+	return turns
+}
+
+// Undo takes back the last turn.
+// Returns an error if there is no turn to take back.
+func (g *Game) Undo() error {
+	if g.Ply() <= 0 {
+		return errors.New("Undo: no turn to undo")
+	}
+	return g.JumpTo(g.Ply() - 1)
+}
+
+// Redo replays the last turn which was taken back.
+// Returns an error if there is no turn to replay.
+func (g *Game) Redo() error {
+	if len(g.undone) == 0 {
+		return errors.New("Redo: no turn to redo")
+	}
+	return g.JumpTo(g.Ply() + 1)
+}
+
+// JumpTo undoes or redoes turns until the game is at the given ply.
+// Returns an error if the ply is outside the game's history.
+//
+// Any changes made since the end of the last turn are lost.
+func (g *Game) JumpTo(ply int) error {
+	if ply < 0 || ply > g.Ply()+len(g.undone) {
+		return fmt.Errorf("JumpTo: no such ply %d", ply)
+	}
+	g.begin()
+	for g.history.ply > ply {
+		g.seen[g.history.key]--
+		g.undone = append(g.undone, g.history)
+		g.history = g.history.prev
+	}
+	for g.history.ply < ply {
+		g.history = g.undone[len(g.undone)-1]
+		g.undone = g.undone[:len(g.undone)-1]
+		g.seen[g.history.key]++
+	}
+	g.setState(g.history.game.snapshot())
+	return nil
+}
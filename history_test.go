@@ -0,0 +1,87 @@
+package homeworlds
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	g := game.Copy()
+	g.ClearHistory()
+	start := printString(g)
+
+	if err := g.Trade(G1, g.Stars["orion"], B1); err != nil {
+		t.Fatal(err)
+	}
+	g.EndTurn()
+	after := printString(g)
+	if after == start {
+		t.Fatal("trade had no effect")
+	}
+
+	if err := g.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := printString(g); got != start {
+		t.Errorf("after Undo:\n%s\nwant:\n%s", got, start)
+	}
+	if err := g.Undo(); err == nil {
+		t.Errorf("expected error undoing past the start")
+	}
+
+	if err := g.Redo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := printString(g); got != after {
+		t.Errorf("after Redo:\n%s\nwant:\n%s", got, after)
+	}
+	if g.Ply() != 1 || len(g.History()) != 2 {
+		t.Errorf("Ply() = %d, len(History()) = %d; want 1, 2", g.Ply(), len(g.History()))
+	}
+
+	// taking a new turn discards the redo history
+	g.JumpTo(0)
+	g.EndTurn()
+	if err := g.Redo(); err == nil {
+		t.Errorf("expected error redoing after a new turn")
+	}
+}
+
+func printString(g *Game) string {
+	var buf bytes.Buffer
+	Print(&buf, g)
+	return buf.String()
+}
+
+func TestUndoWithoutHistory(t *testing.T) {
+	// game was built without NewGame, so its history starts lazily
+	g := game.Copy()
+	start := printString(g)
+	if err := g.Trade(G1, g.Stars["orion"], B1); err != nil {
+		t.Fatal(err)
+	}
+	g.EndTurn()
+	if err := g.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := printString(g); got != start {
+		t.Errorf("after Undo:\n%s\nwant:\n%s", got, start)
+	}
+}
+
+func TestHistoryShared(t *testing.T) {
+	g := NewGame(2)
+	g.EndTurn()
+	h := g.Copy()
+	g.EndTurn()
+	h.Undo()
+	h.EndTurn()
+	h.EndTurn()
+	if g.Ply() != 2 || len(g.History()) != 3 || g.Repetitions() != 2 {
+		t.Errorf("original game changed by its copy: ply %d, %d states, %d repetitions",
+			g.Ply(), len(g.History()), g.Repetitions())
+	}
+	if h.Ply() != 2 || len(h.Turns()) != 2 {
+		t.Errorf("copy has ply %d and %d turns, want 2 and 2", h.Ply(), len(h.Turns()))
+	}
+}
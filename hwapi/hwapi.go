@@ -0,0 +1,28 @@
+// Hwapi serves an HTTP/JSON interface to the Homeworlds rules and AI.
+// See homeworlds.APIHandler for the endpoints.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/magical/homeworlds"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen on `address`")
+	maxDepth := flag.Int("maxdepth", 6, "greatest search `depth` a request may ask for (0 for no limit)")
+	maxQDepth := flag.Int("maxqdepth", 8, "greatest quiescence search `depth` a request may ask for (0 for no limit)")
+	maxTime := flag.Duration("maxtime", 10*time.Second, "longest search `time` a request may ask for (0 for no limit)")
+	h := homeworlds.NewAPIHandler()
+	h.Config.AddFlags(flag.CommandLine)
+	flag.Parse()
+	h.MaxDepth = *maxDepth
+	h.MaxQuiescenceDepth = *maxQDepth
+	h.MaxTime = *maxTime
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, h))
+}
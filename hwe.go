@@ -0,0 +1,440 @@
+package homeworlds
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The engine protocol lets a program drive an AI over a pipe,
+// in the style of chess's UCI.
+// The controller sends commands to the engine one per line,
+// and the engine replies with lines of its own.
+//
+//	hwe
+//		The engine replies with "id name <name>",
+//		an "option name <name> default <value>" line for each setting,
+//		and finally "hweok".
+//	isready
+//		The engine replies "readyok".
+//	setoption name <name> value <value>
+//		Changes a setting, such as depth or time (see AIConfig.Set),
+//		or the repetition policy.
+//	newgame
+//	position startpos
+//	position fen <position string>
+//		Starts a new game, from the beginning or from a position string.
+//	moves <turn>, <turn>, ...
+//		Plays turns, written in the notation of ParseTurn
+//		and separated by commas, from the current position.
+//		If any of the turns can't be played, none of them are.
+//	go [depth <n>] [movetime <ms>]
+//		Searches for the best turn for the current player.
+//		While searching, the engine sends lines like
+//		"info depth 3 score 0.250 nodes 1234 time 56 pv <turn>, <turn>, ..."
+//		and it finishes with "bestmove <turn>",
+//		or "bestmove none" if it can't move.
+//	stop
+//		Ends the search early.
+//	quit
+//		Stops the engine, and the search if there is one.
+//
+// Errors are reported as "info string error: <message>".
+
+// engineDepth is the depth an engine searches to
+// when it is given only a time limit.
+const engineDepth = 64
+
+// noTimeLimit is the time limit of a search which can only be stopped.
+const noTimeLimit = 365 * 24 * time.Hour
+
+// An engine answers the engine protocol.
+type engine struct {
+	out  io.Writer
+	cfg  AIConfig
+	game *Game
+
+	mu   sync.Mutex // guards out and stop
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// RunEngine reads engine protocol commands from in and writes replies to out,
+// searching with an AI configured by cfg, until it reads quit or reaches EOF.
+func RunEngine(in io.Reader, out io.Writer, cfg AIConfig) error {
+	e := &engine{out: out, cfg: cfg, game: NewGame(2)}
+	defer e.wait()
+	defer e.halt()
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case "stop", "isready", "quit":
+			// answered during a search; quit stops it
+		default:
+			e.wait()
+		}
+		var err error
+		switch f[0] {
+		case "hwe":
+			e.printf("id name homeworlds")
+			for _, opt := range strings.Split(e.cfg.String(), ",") {
+				i := strings.Index(opt, "=")
+				e.printf("option name %s default %s", opt[:i], opt[i+1:])
+			}
+			e.printf("option name repetition default %s", e.game.Repetition)
+			e.printf("hweok")
+		case "isready":
+			e.printf("readyok")
+		case "setoption":
+			err = e.setoption(f[1:])
+		case "newgame":
+			e.newgame(NewGame(2))
+		case "position":
+			err = e.position(f[1:])
+		case "moves":
+			err = e.moves(strings.Join(f[1:], " "))
+		case "go":
+			err = e.start(f[1:])
+		case "stop":
+			e.halt()
+		case "quit":
+			return nil
+		default:
+			err = fmt.Errorf("unknown command %q", f[0])
+		}
+		if err != nil {
+			e.printf("info string error: %v", err)
+		}
+	}
+	return sc.Err()
+}
+
+func (e *engine) printf(format string, args ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.out, format+"\n", args...)
+}
+
+// wait waits for the search to finish.
+func (e *engine) wait() {
+	e.wg.Wait()
+}
+
+// halt stops the search, if there is one.
+func (e *engine) halt() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+}
+
+func (e *engine) setoption(args []string) error {
+	if len(args) < 4 || args[0] != "name" || args[2] != "value" {
+		return errors.New("usage: setoption name <name> value <value>")
+	}
+	name, value := args[1], strings.Join(args[3:], " ")
+	if name == "repetition" {
+		return e.game.Repetition.Set(value)
+	}
+	return e.cfg.Set(name, value)
+}
+
+// newgame replaces the game, keeping the repetition policy.
+func (e *engine) newgame(g *Game) {
+	g.Repetition = e.game.Repetition
+	e.game = g
+}
+
+func (e *engine) position(args []string) error {
+	if len(args) == 1 && args[0] == "startpos" {
+		e.newgame(NewGame(2))
+		return nil
+	}
+	if len(args) > 1 && args[0] == "fen" {
+		g, err := ParseFEN(strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		e.newgame(g)
+		return nil
+	}
+	return errors.New("usage: position startpos | position fen <position>")
+}
+
+// moves plays a list of turns.
+// If any turn can't be played, none of them are.
+func (e *engine) moves(s string) error {
+	g := e.game.Copy()
+	for _, part := range strings.Split(s, ",") {
+		t, err := ParseTurn(part)
+		if err != nil {
+			return err
+		}
+		if err := g.Play(t); err != nil {
+			return fmt.Errorf("%s: %v", t, err)
+		}
+	}
+	e.game = g
+	return nil
+}
+
+// start begins searching in the background.
+// The search always deepens iteratively so that it can be stopped.
+func (e *engine) start(args []string) error {
+	cfg := e.cfg
+	depth, movetime := 0, 0
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return fmt.Errorf("go: %s needs a value", args[i])
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("go: invalid %s %q", args[i], args[i+1])
+		}
+		switch args[i] {
+		case "depth":
+			depth = n
+		case "movetime":
+			movetime = n
+		default:
+			return fmt.Errorf("go: unknown parameter %q", args[i])
+		}
+	}
+	if movetime > 0 {
+		cfg.TimeLimit = time.Duration(movetime) * time.Millisecond
+		cfg.Depth = engineDepth
+	}
+	if depth > 0 {
+		cfg.Depth = depth
+	}
+	if cfg.TimeLimit <= 0 {
+		cfg.TimeLimit = noTimeLimit
+	}
+	ai, err := NewAIWithConfig(cfg)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	ai.SetStop(stop)
+	e.mu.Lock()
+	e.stop = stop
+	e.mu.Unlock()
+	e.wg.Add(1)
+	go e.search(ai, e.game.Copy())
+	return nil
+}
+
+func (e *engine) search(ai *AI, g *Game) {
+	defer e.wg.Done()
+	defer e.halt()
+	t, err := e.think(ai, g)
+	if err != nil {
+		e.printf("info string error: %v", err)
+		e.printf("bestmove none")
+		return
+	}
+	e.printf("bestmove %s", t)
+}
+
+func (e *engine) think(ai *AI, g *Game) (Turn, error) {
+	if g.IsOver() {
+		return nil, errors.New("the game is over")
+	}
+	if g.Homeworld(g.CurrentPlayer) == nil {
+		return ai.ChooseHomeworld(g)
+	}
+	start := time.Now()
+	ai.SetLogger(LoggerFunc(func(ev Event) {
+		if ev.Type == DepthDone {
+			e.printf("info depth %d score %.3f nodes %d time %d pv %s",
+				ev.Depth, ev.Score, ev.Visited, ev.Elapsed/time.Millisecond,
+				formatPV(g, ai.PrincipalVariation()))
+		}
+	}))
+	ai.SetHistory(g)
+	a, v := ai.Search(PositionFromGame(g), BasicAction{})
+	ai.SetLogger(nil)
+	e.printf("info depth %d score %.3f time %d pv %s",
+		ai.reached, v, time.Since(start)/time.Millisecond, formatPV(g, ai.PrincipalVariation()))
+	return g.ActionTurn(a)
+}
+
+// formatPV formats a line of play from g as a list of turns.
+func formatPV(g *Game, pv []Action) string {
+	var turns []string
+	g = g.Copy()
+	for _, a := range pv {
+		t, err := g.ActionTurn(a)
+		if err != nil || g.Apply(a) != nil {
+			break
+		}
+		turns = append(turns, t.String())
+	}
+	return strings.Join(turns, ", ")
+}
+
+// An ExternalEngine is a program which speaks the engine protocol.
+type ExternalEngine struct {
+	// Name is the name the engine gave for itself.
+	Name string
+
+	// Info, if not nil, is called with each info line
+	// the engine sends while searching.
+	Info func(line string)
+
+	w   io.Writer
+	r   *bufio.Scanner
+	cmd *exec.Cmd
+	in  io.Closer
+}
+
+// StartEngine runs an engine program with the given arguments
+// and waits for it to identify itself.
+func StartEngine(name string, args ...string) (*ExternalEngine, error) {
+	cmd := exec.Command(name, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	e, err := newExternalEngine(out, in)
+	if err != nil {
+		in.Close()
+		cmd.Wait()
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	e.cmd = cmd
+	e.in = in
+	return e, nil
+}
+
+// newExternalEngine greets an engine which reads commands from w
+// and replies on r.
+func newExternalEngine(r io.Reader, w io.Writer) (*ExternalEngine, error) {
+	e := &ExternalEngine{w: w, r: bufio.NewScanner(r)}
+	if err := e.send("hwe"); err != nil {
+		return nil, err
+	}
+	for {
+		line, err := e.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "hweok" {
+			return e, nil
+		}
+		if strings.HasPrefix(line, "id name ") {
+			e.Name = strings.TrimPrefix(line, "id name ")
+		}
+	}
+}
+
+func (e *ExternalEngine) send(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(e.w, format+"\n", args...)
+	return err
+}
+
+func (e *ExternalEngine) readLine() (string, error) {
+	if !e.r.Scan() {
+		if err := e.r.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("engine exited")
+	}
+	return strings.TrimSpace(e.r.Text()), nil
+}
+
+// SetOption changes one of the engine's settings.
+func (e *ExternalEngine) SetOption(name, value string) error {
+	return e.send("setoption name %s value %s", name, value)
+}
+
+// Think sends the game to the engine and asks it for the current player's turn.
+// The search is limited to the given depth and time, if they are not zero.
+func (e *ExternalEngine) Think(g *Game, depth int, movetime time.Duration) (Turn, error) {
+	rec, err := g.Record()
+	if err != nil {
+		return nil, err
+	}
+	if fen := rec.Headers["Position"]; fen != "" {
+		err = e.send("position fen %s", fen)
+	} else {
+		err = e.send("position startpos")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.Turns) > 0 {
+		var turns []string
+		for _, t := range rec.Turns {
+			turns = append(turns, t.String())
+		}
+		if err := e.send("moves %s", strings.Join(turns, ", ")); err != nil {
+			return nil, err
+		}
+	}
+	cmd := "go"
+	if depth > 0 {
+		cmd += fmt.Sprintf(" depth %d", depth)
+	}
+	if movetime > 0 {
+		cmd += fmt.Sprintf(" movetime %d", movetime/time.Millisecond)
+	}
+	if err := e.send(cmd); err != nil {
+		return nil, err
+	}
+	var lastErr string
+	for {
+		line, err := e.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "info ") {
+			if strings.HasPrefix(line, "info string error: ") {
+				lastErr = strings.TrimPrefix(line, "info string error: ")
+			}
+			if e.Info != nil {
+				e.Info(line)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "bestmove ") {
+			move := strings.TrimPrefix(line, "bestmove ")
+			if move == "none" {
+				if lastErr != "" {
+					return nil, fmt.Errorf("engine has no move: %s", lastErr)
+				}
+				return nil, errors.New("engine has no move")
+			}
+			return ParseTurn(move)
+		}
+	}
+}
+
+// Close tells the engine to quit and waits for it to exit.
+func (e *ExternalEngine) Close() error {
+	e.send("quit")
+	if e.cmd == nil {
+		return nil
+	}
+	e.in.Close()
+	return e.cmd.Wait()
+}
@@ -0,0 +1,155 @@
+package homeworlds
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startTestEngine(t *testing.T) (*ExternalEngine, io.Closer) {
+	cr, ew := io.Pipe()
+	er, cw := io.Pipe()
+	go func() {
+		RunEngine(er, ew, DefaultAIConfig())
+		ew.Close()
+	}()
+	e, err := newExternalEngine(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, cw
+}
+
+func TestEngine(t *testing.T) {
+	e, in := startTestEngine(t)
+	defer in.Close()
+	if e.Name != "homeworlds" {
+		t.Errorf("engine is named %q", e.Name)
+	}
+	var info []string
+	e.Info = func(line string) { info = append(info, line) }
+
+	g := NewGame(2)
+	for i := 0; i < 4; i++ {
+		turn, err := e.Think(g, 1, 0)
+		if err != nil {
+			t.Fatalf("turn %d: %v", i+1, err)
+		}
+		if err := g.Play(turn); err != nil {
+			t.Fatalf("turn %d: %s: %v", i+1, turn, err)
+		}
+	}
+	if len(info) == 0 || !strings.Contains(info[len(info)-1], " pv ") {
+		t.Errorf("got info %q, want a principal variation", info)
+	}
+
+	// An engine can't move once the game is over.
+	g, err := ParseFEN("233/333/233/323 @N=R1:-:- @S=B2:-:G1 N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn, err := e.Think(g, 1, 0); err == nil {
+		t.Errorf("engine played %s after the game was over", turn)
+	}
+}
+
+func TestEngineStop(t *testing.T) {
+	e, in := startTestEngine(t)
+	defer in.Close()
+	fmt.Fprintln(e.w, "position fen 233/332/331/313 @N=R1B2:G3:- @S=Y3B2:-:G3 N")
+	fmt.Fprintln(e.w, "go depth 30")
+	time.Sleep(50 * time.Millisecond)
+	fmt.Fprintln(e.w, "stop")
+	done := make(chan string)
+	go func() {
+		for {
+			line, err := e.readLine()
+			if err != nil || strings.HasPrefix(line, "bestmove") {
+				done <- line
+				return
+			}
+		}
+	}()
+	select {
+	case line := <-done:
+		if line == "bestmove none" || !strings.HasPrefix(line, "bestmove ") {
+			t.Errorf("got %q, want a best move", line)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("engine did not stop")
+	}
+}
+
+func TestEngineQuit(t *testing.T) {
+	cr, ew := io.Pipe()
+	er, cw := io.Pipe()
+	defer cw.Close()
+	go io.Copy(io.Discard, cr)
+	done := make(chan error)
+	go func() {
+		done <- RunEngine(er, ew, DefaultAIConfig())
+	}()
+	fmt.Fprintln(cw, "go depth 30")
+	fmt.Fprintln(cw, "quit")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("engine did not quit during a search")
+	}
+}
+
+// runCommand sends a command to the engine
+// and returns the errors it reports.
+// The pipe is unbuffered, so isready is sent along with the command.
+func runCommand(t *testing.T, e *ExternalEngine, cmd string) []string {
+	var errs []string
+	e.send("%s\nisready", cmd)
+	for {
+		line, err := e.readLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "readyok" {
+			return errs
+		}
+		if strings.HasPrefix(line, "info string error: ") {
+			errs = append(errs, line)
+		}
+	}
+}
+
+func TestEngineMoves(t *testing.T) {
+	e, in := startTestEngine(t)
+	defer in.Close()
+	if errs := runCommand(t, e, "moves homeworld R1 B2 G3 north, homeworld R1 B2 G3 north"); len(errs) != 1 {
+		t.Fatalf("got errors %q, want one", errs)
+	}
+	// none of the turns were played, so North can still play the first
+	if errs := runCommand(t, e, "moves homeworld R1 B2 G3 north"); len(errs) != 0 {
+		t.Errorf("got errors %q after a failed moves command", errs)
+	}
+}
+
+func TestEngineSetOption(t *testing.T) {
+	e, in := startTestEngine(t)
+	defer in.Close()
+	if errs := runCommand(t, e, "setoption name depth value 0"); len(errs) != 1 {
+		t.Fatalf("got errors %q for depth 0, want one", errs)
+	}
+	// the bad value was not kept, so the engine can still search
+	g := NewGame(2)
+	if err := g.Play(Turn{{Type: Homeworld, Star: [2]Piece{R1, B2}, Ship: G3, System: "North"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Play(Turn{{Type: Homeworld, Star: [2]Piece{Y1, B3}, Ship: G3, System: "South"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Think(g, 0, 0); err != nil {
+		t.Errorf("search after a rejected option: %v", err)
+	}
+}
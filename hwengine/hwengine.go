@@ -0,0 +1,27 @@
+// Hwengine runs the Homeworlds AI as an engine which speaks
+// the engine protocol on standard input and output,
+// so that other programs can play against it.
+// See homeworlds.RunEngine for the protocol.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/magical/homeworlds"
+)
+
+func main() {
+	cfg := homeworlds.DefaultAIConfig()
+	cfg.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := homeworlds.NewAIWithConfig(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := homeworlds.RunEngine(os.Stdin, os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
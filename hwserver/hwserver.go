@@ -0,0 +1,37 @@
+// Hwserver hosts games of Homeworlds between players who connect over TCP.
+// Clients are paired up in the order they connect.
+// See homeworlds.Message for the protocol.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/magical/homeworlds"
+)
+
+func main() {
+	addr := flag.String("addr", ":7474", "listen on `address`")
+	var repetition homeworlds.RepetitionPolicy
+	flag.Var(&repetition, "repetition", "repeated position `policy` (allow, draw, forbid)")
+	turnTime := flag.Duration("turntime", 0, "time limit for each turn (0 for none)")
+	writeTimeout := flag.Duration("writetimeout", 30*time.Second, "time limit for sending a message to a client")
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	logger.Printf("listening on %s", l.Addr())
+	s := &homeworlds.Server{
+		Repetition:   repetition,
+		TurnTimeout:  *turnTime,
+		WriteTimeout: *writeTimeout,
+		ErrorLog:     logger,
+	}
+	log.Fatal(s.Serve(l))
+}
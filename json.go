@@ -0,0 +1,353 @@
+package homeworlds
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONVersion is the version of the JSON encoding of games and actions.
+// It is stored in the "version" field of each encoded game.
+//
+// A game is encoded like this:
+//
+//	{
+//	  "version": 1,
+//	  "numPlayers": 2,
+//	  "phase": 0,
+//	  "currentPlayer": "North",
+//	  "repetition": "allow",
+//	  "bank": {"B1": 2, "B2": 3, ...},
+//	  "homeworlds": {"North": "north", "South": "south"},
+//	  "stars": [
+//	    {"name": "alpha", "pieces": ["R1"], "ships": {"South": ["G1"]}},
+//	    {"name": "north", "homeworld": true, "pieces": ["G3", "Y1"], "ships": {"North": ["B3", "B1"]}},
+//	    ...
+//	  ]
+//	}
+//
+// The stars are sorted by name.
+// The game's history is not included.
+//
+// Actions, as chosen by the AI, refer to stars by their index in a Position:
+//
+//	{"type": "build", "system": 0, "ship": "B1"}
+//	{"type": "trade", "system": 0, "ship": "B3", "newShip": "G3"}
+//	{"type": "move", "system": 2, "ship": "G1", "to": 0}
+//	{"type": "discover", "system": 0, "ship": "B1", "newStar": "R3"}
+//	{"type": "attack", "system": 1, "ship": "G1"}
+//	{"type": "sacrifice", "system": 0, "ship": "Y2", "actions": [{"type": "move", ...}, ...]}
+//	{"type": "pass"}
+//
+// Commands are encoded as strings in the notation of Command.String,
+// and turns as lists of commands.
+const JSONVersion = 1
+
+func (p Piece) MarshalText() ([]byte, error) {
+	if p >= 12 {
+		return nil, fmt.Errorf("invalid piece %d", p)
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *Piece) UnmarshalText(b []byte) error {
+	q, err := ParsePiece(string(b))
+	if err != nil {
+		return err
+	}
+	*p = q
+	return nil
+}
+
+func (pl Player) MarshalText() ([]byte, error) {
+	if pl != North && pl != South {
+		return nil, fmt.Errorf("invalid player %d", pl)
+	}
+	return []byte(pl.String()), nil
+}
+
+func (pl *Player) UnmarshalText(b []byte) error {
+	switch strings.ToLower(string(b)) {
+	case "north":
+		*pl = North
+	case "south":
+		*pl = South
+	default:
+		return fmt.Errorf("invalid player %q", b)
+	}
+	return nil
+}
+
+func (c Color) MarshalText() ([]byte, error) {
+	if c > Blue {
+		return nil, fmt.Errorf("invalid color %d", c)
+	}
+	return []byte(strings.ToLower(c.String())), nil
+}
+
+func (c *Color) UnmarshalText(b []byte) error {
+	d, err := ParseColor(string(b))
+	if err != nil {
+		return err
+	}
+	*c = d
+	return nil
+}
+
+var actionTypeNames = map[ActionType]string{
+	Pass:       "pass",
+	Build:      "build",
+	Move:       "move",
+	Discover:   "discover",
+	Trade:      "trade",
+	Attack:     "attack",
+	Catastrope: "catastrophe",
+	Sacrifice:  "sacrifice",
+	Homeworld:  "homeworld",
+}
+
+func (t ActionType) MarshalText() ([]byte, error) {
+	if s, ok := actionTypeNames[t]; ok {
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("invalid action type %d", t)
+}
+
+func (t *ActionType) UnmarshalText(b []byte) error {
+	for u, s := range actionTypeNames {
+		if s == string(b) {
+			*t = u
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid action type %q", b)
+}
+
+func (p RepetitionPolicy) MarshalText() ([]byte, error) {
+	if s, ok := repetitionNames[p]; ok {
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("invalid repetition policy %d", p)
+}
+
+func (p *RepetitionPolicy) UnmarshalText(b []byte) error {
+	return p.Set(string(b))
+}
+
+func (c Command) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Command) UnmarshalText(b []byte) error {
+	d, _, err := parseCommand(strings.Fields(string(b)))
+	if err != nil {
+		return err
+	}
+	*c = d
+	return nil
+}
+
+type jsonGame struct {
+	Version       int               `json:"version"`
+	NumPlayers    int               `json:"numPlayers"`
+	Phase         int               `json:"phase"`
+	CurrentPlayer Player            `json:"currentPlayer"`
+	Repetition    RepetitionPolicy  `json:"repetition"`
+	Bank          map[Piece]int     `json:"bank"`
+	Homeworlds    map[Player]string `json:"homeworlds"`
+	Stars         []*Star           `json:"stars"`
+}
+
+func (g *Game) MarshalJSON() ([]byte, error) {
+	v := jsonGame{
+		Version:       JSONVersion,
+		NumPlayers:    g.NumPlayers,
+		Phase:         g.Phase,
+		CurrentPlayer: g.CurrentPlayer,
+		Repetition:    g.Repetition,
+		Bank:          g.Bank,
+		Homeworlds:    g.Homeworlds,
+		Stars:         []*Star{},
+	}
+	for _, name := range g.sortedStars() {
+		v.Stars = append(v.Stars, g.Stars[name])
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a game.
+// The game's history starts at the decoded state.
+func (g *Game) UnmarshalJSON(b []byte) error {
+	var v jsonGame
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Version != JSONVersion {
+		return fmt.Errorf("homeworlds: unsupported game version %d", v.Version)
+	}
+	if v.NumPlayers != 2 {
+		return fmt.Errorf("homeworlds: invalid number of players %d", v.NumPlayers)
+	}
+	h := Game{
+		Phase:         v.Phase,
+		NumPlayers:    v.NumPlayers,
+		CurrentPlayer: v.CurrentPlayer,
+		Repetition:    v.Repetition,
+		Bank:          make(map[Piece]int),
+		Homeworlds:    make(map[Player]string),
+		Stars:         make(map[string]*Star),
+	}
+	for p, n := range v.Bank {
+		h.Bank[p] = n
+	}
+	for pl, name := range v.Homeworlds {
+		h.Homeworlds[pl] = name
+	}
+	for _, s := range v.Stars {
+		if s == nil || s.Name == "" {
+			return fmt.Errorf("homeworlds: star has no name")
+		}
+		if _, dup := h.Stars[s.Name]; dup {
+			return fmt.Errorf("homeworlds: duplicate star %q", s.Name)
+		}
+		if s.Ships == nil {
+			s.Ships = make(map[Player][]Piece)
+		}
+		h.Stars[s.Name] = s
+	}
+	// A homeworld may have been destroyed, but it can't be an ordinary star.
+	for pl, name := range h.Homeworlds {
+		if s, ok := h.Stars[name]; ok && !s.IsHomeworld {
+			return fmt.Errorf("homeworlds: %s's homeworld %q is not a homeworld", pl, name)
+		}
+	}
+	h.ClearHistory()
+	*g = h
+	return nil
+}
+
+type jsonAction struct {
+	Type    ActionType    `json:"type"`
+	System  *int          `json:"system,omitempty"`
+	Ship    *Piece        `json:"ship,omitempty"`
+	NewShip *Piece        `json:"newShip,omitempty"`
+	NewStar *Piece        `json:"newStar,omitempty"`
+	To      *int          `json:"to,omitempty"`
+	Actions []BasicAction `json:"actions,omitempty"`
+}
+
+func (b BasicAction) json() jsonAction {
+	v := jsonAction{Type: b.Type()}
+	if b.Type() == Pass {
+		return v
+	}
+	system, ship, arg := b.System(), b.Ship(), b.NewShip()
+	v.System, v.Ship = &system, &ship
+	switch b.Type() {
+	case Trade:
+		v.NewShip = &arg
+	case Discover:
+		v.NewStar = &arg
+	case Move:
+		to := b.ToSystem()
+		v.To = &to
+	}
+	return v
+}
+
+func (v *jsonAction) basic() (BasicAction, error) {
+	var b BasicAction
+	if v.Type == Pass {
+		return b, nil
+	}
+	if v.System == nil || v.Ship == nil {
+		return b, fmt.Errorf("homeworlds: %s action needs a system and a ship", actionTypeNames[v.Type])
+	}
+	if *v.System < 0 || *v.System > 255 {
+		return b, fmt.Errorf("homeworlds: invalid system %d", *v.System)
+	}
+	var arg Piece
+	switch v.Type {
+	case Build, Attack, Sacrifice:
+	case Trade:
+		if v.NewShip == nil {
+			return b, fmt.Errorf("homeworlds: trade action needs a new ship")
+		}
+		arg = *v.NewShip
+	case Discover:
+		if v.NewStar == nil {
+			return b, fmt.Errorf("homeworlds: discover action needs a new star")
+		}
+		arg = *v.NewStar
+	case Move:
+		if v.To == nil || *v.To < 0 || *v.To > 255 {
+			return b, fmt.Errorf("homeworlds: move action needs a destination")
+		}
+		arg = Piece(*v.To)
+	default:
+		return b, fmt.Errorf("homeworlds: invalid action type %s", actionTypeNames[v.Type])
+	}
+	return mkbasic(v.Type, *v.Ship, *v.System, arg), nil
+}
+
+func (b BasicAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.json())
+}
+
+func (b *BasicAction) UnmarshalJSON(data []byte) error {
+	var v jsonAction
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Type == Sacrifice || len(v.Actions) > 0 {
+		return fmt.Errorf("homeworlds: sacrifice is not a basic action")
+	}
+	c, err := v.basic()
+	if err != nil {
+		return err
+	}
+	*b = c
+	return nil
+}
+
+func (a Action) MarshalJSON() ([]byte, error) {
+	v := a.Basic().json()
+	if a.Type() == Sacrifice {
+		v.Actions = a.actions[:a.N()]
+		if len(v.Actions) == 0 {
+			v.Actions = nil
+		}
+	}
+	return json.Marshal(v)
+}
+
+func (a *Action) UnmarshalJSON(data []byte) error {
+	var v jsonAction
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Type != Sacrifice {
+		if len(v.Actions) > 0 {
+			return fmt.Errorf("homeworlds: only a sacrifice can have follow-up actions")
+		}
+		b, err := v.basic()
+		if err != nil {
+			return err
+		}
+		*a = b.Action()
+		return nil
+	}
+	if len(v.Actions) > len(a.actions) {
+		return fmt.Errorf("homeworlds: too many follow-up actions")
+	}
+	b, err := v.basic()
+	if err != nil {
+		return err
+	}
+	s := mksacrifice(b.Ship(), b.System())
+	for _, b := range v.Actions {
+		s = s.append(b)
+	}
+	*a = s
+	return nil
+}
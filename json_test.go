@@ -0,0 +1,127 @@
+package homeworlds
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGameJSON(t *testing.T) {
+	rec, err := ReadSDG(strings.NewReader(sdgLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Game
+	if err := json.Unmarshal(b, &h); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := printString(&h), printString(g); got != want {
+		t.Errorf("round trip changed the game:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := PositionFromGame(&h), PositionFromGame(g); !got.Equal(want) {
+		t.Errorf("round trip changed the position: got %v, want %v", got, want)
+	}
+	c, err := json.Marshal(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(c) {
+		t.Errorf("encoding is not stable:\n%s\n%s", b, c)
+	}
+
+	bad := strings.Replace(string(b), `"version":1`, `"version":99`, 1)
+	if err := json.Unmarshal([]byte(bad), &h); err == nil {
+		t.Errorf("unmarshal accepted version 99")
+	}
+}
+
+func TestActionJSON(t *testing.T) {
+	s := mksacrifice(Y3, 0)
+	s = s.append(mkmove(G1, 0, 2))
+	s = s.append(mkbasic(Trade, B1, 2, G1))
+	actions := []Action{
+		BasicAction{}.Action(),
+		mkbasic(Build, G1, 1, 0).Action(),
+		mkbasic(Trade, B3, 0, G3).Action(),
+		mkbasic(Discover, B1, 0, R3).Action(),
+		mkbasic(Attack, R1, 2, 0).Action(),
+		mkmove(G2, 1, 3).Action(),
+		s,
+	}
+	for _, a := range actions {
+		b, err := json.Marshal(a)
+		if err != nil {
+			t.Errorf("%v: %v", a, err)
+			continue
+		}
+		var got Action
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("%s: %v", b, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, a) {
+			t.Errorf("%s: got %v, want %v", b, got, a)
+		}
+	}
+
+	var a Action
+	if err := json.Unmarshal([]byte(`{"type":"move","system":1,"ship":"G1"}`), &a); err == nil {
+		t.Errorf("unmarshal accepted a move without a destination")
+	}
+}
+
+func TestTurnJSON(t *testing.T) {
+	turn, err := ParseTurn("sacrifice Y3 north; move G1 north alpha; catastrophe red alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(turn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `["sacrifice Y3 north","move G1 north alpha","catastrophe red alpha"]`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+	var got Turn
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, turn) {
+		t.Errorf("got %v, want %v", got, turn)
+	}
+}
+
+func TestGameJSONDestroyedHomeworld(t *testing.T) {
+	g := NewGame(2)
+	for _, s := range []string{"homeworld R1 B2 G3 north", "homeworld Y1 B3 G3 south"} {
+		turn, err := ParseTurn(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Play(turn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.destroy(g.Homeworld(North))
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Game
+	if err := json.Unmarshal(b, &h); err != nil {
+		t.Fatal(err)
+	}
+	if !h.IsOver() || h.Winner() != South {
+		t.Errorf("decoded game is not won by South:\n%s", printString(&h))
+	}
+}
@@ -0,0 +1,82 @@
+package homeworlds
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// A StarNamer chooses names for newly discovered stars.
+type StarNamer interface {
+	// StarName returns a name for a new star.
+	// Taken reports whether a name is already in use.
+	StarName(taken func(name string) bool) string
+}
+
+// A NameList is a StarNamer which picks the first name in the list
+// which isn't taken.
+// If every name is taken, it adds a number to the end of one.
+type NameList []string
+
+func (l NameList) StarName(taken func(name string) bool) string {
+	for _, name := range l {
+		if !taken(name) {
+			return name
+		}
+	}
+	for n := 2; ; n++ {
+		for _, name := range l {
+			name = fmt.Sprintf("%s-%d", name, n)
+			if !taken(name) {
+				return name
+			}
+		}
+		if len(l) == 0 && !taken(fmt.Sprint(n)) {
+			return fmt.Sprint(n)
+		}
+	}
+}
+
+// DefaultStarNames is the StarNamer used by games without one.
+// It names stars after constellations and real stars.
+var DefaultStarNames = NameList{
+	"Achernar", "Aldebaran", "Altair", "Andromeda", "Antares",
+	"Aquila", "Arcturus", "Auriga", "Bellatrix", "Betelgeuse",
+	"Canopus", "Capella", "Carina", "Cassiopeia", "Castor",
+	"Centaurus", "Cepheus", "Cygnus", "Deneb", "Draco",
+	"Electra", "Eridanus", "Fomalhaut", "Gemini", "Hydra",
+	"Lyra", "Maia", "Merope", "Mira", "Orion",
+	"Pegasus", "Perseus", "Phoenix", "Polaris", "Pollux",
+	"Procyon", "Regulus", "Rigel", "Sirius", "Spica",
+	"Taurus", "Vega", "Vela", "Virgo", "Vulpecula",
+}
+
+// ShuffledStarNames returns the default names in an order
+// determined by the seed.
+func ShuffledStarNames(seed int64) NameList {
+	r := rand.New(rand.NewSource(seed))
+	l := make(NameList, len(DefaultStarNames))
+	for i, j := range r.Perm(len(l)) {
+		l[i] = DefaultStarNames[j]
+	}
+	return l
+}
+
+// newStarName returns a function which names new stars
+// using the game's StarNamer,
+// avoiding the names of the game's stars and of the stars it has already named.
+func (g *Game) newStarName() func() string {
+	namer := g.Namer
+	if namer == nil {
+		namer = DefaultStarNames
+	}
+	used := make(map[string]bool)
+	taken := func(name string) bool {
+		_, ok := g.Stars[name]
+		return ok || used[name] || name == "home"
+	}
+	return func() string {
+		name := namer.StarName(taken)
+		used[name] = true
+		return name
+	}
+}
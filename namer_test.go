@@ -0,0 +1,30 @@
+package homeworlds
+
+import "testing"
+
+func TestStarNamer(t *testing.T) {
+	g, err := ParseFEN("332/232/231/222 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 Achernar=B1:R3:G1 N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newName := g.newStarName()
+	if got := newName(); got != "Aldebaran" {
+		t.Errorf("first name = %q, want Aldebaran", got)
+	}
+	if got := newName(); got != "Altair" {
+		t.Errorf("second name = %q, want Altair", got)
+	}
+
+	a, b := ShuffledStarNames(1), ShuffledStarNames(1)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("ShuffledStarNames is not deterministic: %v, %v", a, b)
+		}
+	}
+
+	l := NameList{"Vega"}
+	taken := map[string]bool{"Vega": true, "Vega-2": true}
+	if got := l.StarName(func(s string) bool { return taken[s] }); got != "Vega-3" {
+		t.Errorf("got %q, want Vega-3", got)
+	}
+}
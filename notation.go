@@ -0,0 +1,173 @@
+package homeworlds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A SyntaxError reports the location of an error in a game record.
+type SyntaxError struct {
+	Line int // line number, starting at 1
+	Col  int // column number, starting at 1
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// ReadRecord reads a game record in the notation used by the play command.
+// The record looks like this:
+//
+//	[North "alice"]
+//	[South "bob"]
+//
+//	homeworld G3 Y1 B3 north  # comments run to the end of the line
+//	homeworld Y3 B2 G3 south
+//	build B1 north
+//	discover G3 south R1 alpha
+//	sacrifice Y1 north; move B1 north alpha
+//
+// Headers, in square brackets, hold a name and a quoted value.
+// Each other line is one turn: a list of commands separated by semicolons,
+// optionally preceded by a turn number such as "3.".
+// The commands are described in Command.String;
+// piece and color names are case-insensitive.
+// If a homeworld is not given a name, it is named after its player.
+//
+// Syntax errors are reported as a *SyntaxError.
+func ReadRecord(r io.Reader) (*Record, error) {
+	rec := &Record{Headers: make(map[string]string)}
+	s := bufio.NewScanner(r)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		line := stripComment(s.Text())
+		col := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsSpace(r) })
+		if col < 0 {
+			continue
+		}
+		if line[col] == '[' {
+			k, v, err := parseHeader(line[col:])
+			if err != nil {
+				return nil, &SyntaxError{lineno, col + 1, err.Error()}
+			}
+			rec.Headers[k] = v
+			continue
+		}
+		rest := trimTurnNumber(line[col:])
+		col += len(line[col:]) - len(rest)
+		t, err := parseTurnAt(rest, lineno, col)
+		if err != nil {
+			return nil, err
+		}
+		rec.Turns = append(rec.Turns, t)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	rec.nameHomeworlds()
+	return rec, nil
+}
+
+// stripComment removes a comment from the end of a line.
+// A # inside a quoted string does not start a comment.
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '#' && !quoted:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseHeader parses a header line of the form [Name "value"].
+func parseHeader(s string) (name, value string, err error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, "]") {
+		return "", "", fmt.Errorf("header: missing ]")
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i <= 0 {
+		return "", "", fmt.Errorf("header: missing value")
+	}
+	name = s[:i]
+	value, err = strconv.Unquote(strings.TrimSpace(s[i:]))
+	if err != nil {
+		return "", "", fmt.Errorf("header %s: value must be a quoted string", name)
+	}
+	return name, value, nil
+}
+
+// parseTurnAt is like ParseTurn, but reports errors
+// relative to the given line and column.
+func parseTurnAt(s string, line, col int) (Turn, error) {
+	var t Turn
+	for _, part := range strings.Split(s, ";") {
+		f, cols := fieldsAt(part, col)
+		c, bad, err := parseCommand(f)
+		if err != nil {
+			if len(cols) > 0 {
+				col = cols[bad]
+			}
+			return nil, &SyntaxError{line, col + 1, err.Error()}
+		}
+		t = append(t, c)
+		col += len(part) + 1
+	}
+	return t, nil
+}
+
+// fieldsAt splits s into fields, like strings.Fields,
+// and returns the column of each field,
+// given that s begins at column col.
+func fieldsAt(s string, col int) ([]string, []int) {
+	var f []string
+	var cols []int
+	start := -1
+	for i, r := range s + " " {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				f = append(f, s[start:i])
+				cols = append(cols, col+start)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	return f, cols
+}
+
+// WriteRecord writes a game record in the notation read by ReadRecord:
+// the headers, sorted by name, followed by one turn per line.
+func WriteRecord(w io.Writer, rec *Record) error {
+	bw := bufio.NewWriter(w)
+	var names []string
+	for k := range rec.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintf(bw, "[%s %q]\n", k, rec.Headers[k])
+	}
+	if len(names) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, t := range rec.Turns {
+		fmt.Fprintln(bw, t)
+	}
+	return bw.Flush()
+}
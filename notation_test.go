@@ -0,0 +1,117 @@
+package homeworlds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var record = `[North "alice"]
+[South "bob"]
+
+homeworld R2 B1 G3 North
+homeworld Y3 B2 G3 South
+build G1 North
+build G1 South
+trade G1 Y1 North
+discover G1 South R1 alpha
+build G1 North
+build G1 alpha
+sacrifice G3 North; build G2 North; build G2 North; build G2 North; catastrophe green North
+`
+
+func TestReadRecord(t *testing.T) {
+	input := strings.Replace(record, "build G1 South", "4. build g1 South # comment", 1)
+	rec, err := ReadRecord(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Headers["North"] != "alice" || rec.Headers["South"] != "bob" {
+		t.Errorf("headers = %v", rec.Headers)
+	}
+
+	hdr, err := ReadRecord(strings.NewReader(`[Event "club \"#1\" # final"]  # comment`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hdr.Headers["Event"], `club "#1" # final`; got != want {
+		t.Errorf("Event header = %q, want %q", got, want)
+	}
+
+	// Same game as the SDG log
+	sdg, err := ReadSDG(strings.NewReader(sdgLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g1, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, _ := sdg.Replay()
+	if printString(g1) != printString(g2) {
+		t.Errorf("record and SDG log differ")
+	}
+
+	var buf bytes.Buffer
+	WriteRecord(&buf, rec)
+	if got := buf.String(); got != record {
+		t.Errorf("WriteRecord:\n%s\nwant:\n%s", got, record)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		in        string
+		line, col int
+	}{
+		{"bulid G1 north", 1, 1},
+		{"\n  build Q1 north", 2, 9},
+		{"build G1 north; trade G1 north", 1, 17},
+		{"1. sacrifice Y3 north; move G1 north alpha; catastrophe pink alpha", 1, 57},
+		{`[North alice]`, 1, 1},
+	}
+	for _, tt := range tests {
+		_, err := ReadRecord(strings.NewReader(tt.in))
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: got %v, want syntax error", tt.in, err)
+			continue
+		}
+		if serr.Line != tt.line || serr.Col != tt.col {
+			t.Errorf("%q: error at %d:%d, want %d:%d (%v)", tt.in, serr.Line, serr.Col, tt.line, tt.col, serr)
+		}
+	}
+}
+
+func TestRecordPosition(t *testing.T) {
+	g, err := ParseFEN("223/222/231/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=R1:Y2:- beta=R2:G1:- N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	turn, err := ParseTurn("sacrifice Y2 alpha; move G1 beta north")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Play(turn); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := g.Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRecord(&buf, rec); err != nil {
+		t.Fatal(err)
+	}
+	rec2, err := ReadRecord(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := rec2.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.FEN() != g.FEN() {
+		t.Errorf("replayed game is %s, want %s", h.FEN(), g.FEN())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/magical/homeworlds"
+)
+
+// A client plays a game hosted by hwserver.
+type client struct {
+	conn   net.Conn
+	enc    *json.Encoder
+	dec    *json.Decoder
+	player homeworlds.Player
+}
+
+// dial connects to a server and waits for the game to start.
+// It returns the new game.
+func dial(addr string, out io.Writer) (*client, *homeworlds.Game, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &client{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(conn),
+	}
+	for {
+		m, err := c.recv()
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		switch m.Type {
+		case "wait":
+			fmt.Fprintln(out, "Waiting for an opponent...")
+		case "start":
+			if m.Player == nil || m.Game == nil {
+				conn.Close()
+				return nil, nil, errors.New("server sent an incomplete start message")
+			}
+			c.player = *m.Player
+			return c, m.Game, nil
+		default:
+			conn.Close()
+			return nil, nil, fmt.Errorf("unexpected message %q from server", m.Type)
+		}
+	}
+}
+
+func (c *client) send(t homeworlds.Turn) error {
+	return c.enc.Encode(homeworlds.Message{Type: "turn", Turn: t})
+}
+
+func (c *client) recv() (homeworlds.Message, error) {
+	var m homeworlds.Message
+	err := c.dec.Decode(&m)
+	if err == io.EOF {
+		err = errors.New("server closed the connection")
+	}
+	return m, err
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A lineReader reads lines typed by the user.
+// When stdin is a terminal, it puts it in non-canonical mode
+// so that pressing tab can complete the word being typed.
+type lineReader struct {
+	in       *bufio.Reader
+	out      io.Writer
+	saved    string // terminal settings to restore, if any
+	complete func() []string
+}
+
+// newLineReader returns a lineReader which completes words
+// from the list returned by complete.
+func newLineReader(complete func() []string) *lineReader {
+	lr := &lineReader{in: bufio.NewReader(os.Stdin), out: os.Stdout, complete: complete}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return lr
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return lr
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return lr
+	}
+	lr.saved = strings.TrimSpace(saved)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		lr.Close()
+		os.Exit(130)
+	}()
+	return lr
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Close restores the terminal settings.
+func (lr *lineReader) Close() {
+	if lr.saved != "" {
+		stty(lr.saved)
+		lr.saved = ""
+	}
+}
+
+// ReadLine prints the prompt and reads a line.
+func (lr *lineReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(lr.out, prompt)
+	if lr.saved == "" {
+		line, err := lr.in.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	var line []byte
+	for {
+		b, err := lr.in.ReadByte()
+		if err != nil {
+			return string(line), err
+		}
+		switch {
+		case b == '\r' || b == '\n':
+			io.WriteString(lr.out, "\n")
+			return string(line), nil
+		case b == 4: // ^D
+			if len(line) == 0 {
+				io.WriteString(lr.out, "\n")
+				return "", io.EOF
+			}
+		case b == 0x7f || b == '\b':
+			if len(line) > 0 {
+				_, n := utf8.DecodeLastRune(line)
+				line = line[:len(line)-n]
+				io.WriteString(lr.out, "\b \b")
+			}
+		case b == 0x15: // ^U
+			io.WriteString(lr.out, strings.Repeat("\b \b", utf8.RuneCount(line)))
+			line = line[:0]
+		case b == 0x1b: // ignore escape sequences such as arrow keys
+			if next, _ := lr.in.ReadByte(); next == '[' {
+				lr.in.ReadByte()
+			}
+		case b == '\t':
+			line = lr.tab(prompt, line)
+		case b >= 0x20:
+			line = append(line, b)
+			lr.out.Write([]byte{b})
+		}
+	}
+}
+
+// tab completes the last word of the line.
+// If there is more than one possibility, it lists them.
+func (lr *lineReader) tab(prompt string, line []byte) []byte {
+	start := strings.LastIndexAny(string(line), " ;") + 1
+	word := string(line[start:])
+	var matches []string
+	for _, w := range lr.complete() {
+		if strings.HasPrefix(strings.ToLower(w), strings.ToLower(word)) {
+			matches = append(matches, w)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return line
+	case 1:
+		return lr.replace(line, start, matches[0]+" ")
+	}
+	sort.Strings(matches)
+	prefix := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(strings.ToLower(m), strings.ToLower(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(word) {
+		return lr.replace(line, start, prefix)
+	}
+	io.WriteString(lr.out, "\n"+strings.Join(matches, "  ")+"\n"+prompt+string(line))
+	return line
+}
+
+// replace replaces the end of the line, from start, with s.
+func (lr *lineReader) replace(line []byte, start int, s string) []byte {
+	io.WriteString(lr.out, strings.Repeat("\b", utf8.RuneCount(line[start:]))+s)
+	return append(line[:start], s...)
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bufio"
-	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
@@ -12,190 +10,176 @@ import (
 )
 
 func main() {
-	g := newGame()
-	s := bufio.NewScanner(os.Stdin)
-	for !g.IsOver() {
-		io.WriteString(os.Stdout, "> ")
-		if !s.Scan() {
-			break
-		}
-		line := s.Text()
-		a, err := parseAction(line)
+	cfg := homeworlds.DefaultAIConfig()
+	cfg.AddFlags(flag.CommandLine)
+	verbose := flag.Bool("v", false, "log the AI's search")
+	var repetition homeworlds.RepetitionPolicy
+	flag.Var(&repetition, "repetition", "repeated position `policy` (allow, draw, forbid)")
+	board := flag.String("board", "prose", "board `style` (prose, grid)")
+	north := flag.String("north", "human", "who plays North: human, or ai[:options] (e.g. ai:depth=4)")
+	south := flag.String("south", "human", "who plays South: human, or ai[:options]")
+	saveFile := flag.String("save", "", "save the game to `file` after every turn")
+	loadFile := flag.String("load", "", "resume the game record or position in `file`")
+	connect := flag.String("connect", "", "play against an opponent on the hwserver at `address`;\nthe -north or -south flag for your color decides who plays")
+	flag.Parse()
+
+	// Settings saved with a game apply unless they were given on the command line.
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	g := homeworlds.NewGame(2)
+	if *loadFile != "" && *connect != "" {
+		fmt.Fprintln(os.Stderr, "can't load a game to play on a server")
+		os.Exit(2)
+	}
+	if *loadFile != "" {
+		var headers map[string]string
+		var err error
+		g, headers, err = loadGame(*loadFile)
 		if err != nil {
-			fmt.Println(err)
-			continue
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		fmt.Println(a)
-		err = do(g, a)
-		if err != nil {
-			fmt.Println(err)
-			continue
+		if err := resume(headers, set, [2]*string{north, south}, &repetition); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *loadFile, err)
+			os.Exit(1)
 		}
-		g.CurrentPlayer = homeworlds.Player((int(g.CurrentPlayer) + 1) % g.NumPlayers)
-		homeworlds.Print(os.Stdout, g)
 	}
-	if s.Err() != nil {
-		fmt.Println(s.Err())
+	printBoard, err := homeworlds.BoardPrinter(*board, isTerminal(os.Stdout))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	if g.IsOver() {
-		fmt.Println("Winner:", g.Winner())
+	ai, err := homeworlds.NewAIWithConfig(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-}
-
-type Action struct {
-	Type      homeworlds.ActionType
-	Ship      homeworlds.Piece
-	System    string
-	NewShip   homeworlds.Piece
-	NewSystem string
-}
-
-const Homeworld homeworlds.ActionType = 99
-
-var parseError = errors.New("parse error")
-
-func parseAction(s string) (Action, error) {
-	parts := strings.Fields(s)
-	//    Homeworld star1 star2 ship
-	//    Discover ship fromSystem newStar newName
-	//    Move ship fromSystem toSystem
-	//    Build ship inSystem
-	//    Trade oldShip newShip inSystem
-	//    Attack ship inSystem
-	//    Sacrifice ship inSystem
-	//    Catastrophe color inSystem
-	var a Action
-	var err error
-	switch {
-	case len(parts) == 3 && parts[0] == "build":
-		a.Type = homeworlds.Build
-		a.System = parts[2]
-		a.Ship, err = parseShip(parts[1])
+	var seats [2]*homeworlds.AI
+	for i, spec := range []string{*north, *south} {
+		seats[i], err = parseSeat(spec, cfg)
 		if err != nil {
-			goto fail
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
-		return a, nil
-	case len(parts) == 4 && parts[0] == "trade":
-		a.Type = homeworlds.Trade
-		a.System = parts[3]
-		a.Ship, err = parseShip(parts[1])
-		if err != nil {
-			goto fail
-		}
-		a.NewShip, err = parseShip(parts[2])
-		if err != nil {
-			goto fail
-		}
-		return a, nil
-	case len(parts) == 4 && parts[0] == "move":
-		a.Type = homeworlds.Move
-		a.System = parts[2]
-		a.NewSystem = parts[3]
-		a.Ship, err = parseShip(parts[1])
-		if err != nil {
-			goto fail
-		}
-		return a, nil
-	case len(parts) == 3 && parts[0] == "attack":
-		a.Type = homeworlds.Attack
-		a.System = parts[2]
-		a.Ship, err = parseShip(parts[1])
-		if err != nil {
-			goto fail
-		}
-		return a, nil
-	case len(parts) == 5 && parts[0] == "discover":
-		a.Type = homeworlds.Discover
-		a.System = parts[2]
-		a.NewSystem = parts[4]
-		a.Ship, err = parseShip(parts[1])
-		if err != nil {
-			goto fail
+	}
+	if *verbose {
+		ai.SetLogger(homeworlds.NewTextLogger(os.Stderr))
+		for _, seat := range seats {
+			if seat != nil {
+				seat.SetLogger(homeworlds.NewTextLogger(os.Stderr))
+			}
 		}
-		a.NewShip, err = parseShip(parts[3])
+	}
+
+	var remote *client
+	if *connect != "" {
+		remote, g, err = dial(*connect, os.Stdout)
 		if err != nil {
-			goto fail
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		return a, nil
+		defer remote.Close()
+		fmt.Printf("You are playing %s.\n", remote.player)
+		// The server decides the rules.
+		repetition = g.Repetition
+	}
+	g.Repetition = repetition
+	r := &repl{
+		g:          g,
+		ai:         ai,
+		seats:      seats,
+		printBoard: printBoard,
+		out:        os.Stdout,
+		saveFile:   *saveFile,
+		headers: map[string]string{
+			seatHeader(homeworlds.North): seatSpec(seats[homeworlds.North]),
+			seatHeader(homeworlds.South): seatSpec(seats[homeworlds.South]),
+			"Repetition":                 repetition.String(),
+		},
+		remote: remote,
+	}
+	if remote != nil {
+		// Nobody here knows who the opponent is.
+		delete(r.headers, seatHeader(homeworlds.Player(1-remote.player)))
+	}
+	if *loadFile != "" {
+		printBoard(os.Stdout, g)
+	}
+	lr := newLineReader(r.completions)
+	err = r.run(lr)
+	lr.Close()
+	if err != nil {
+		fmt.Println(err)
+	}
+	g = r.g
+	if g.IsDraw() {
+		fmt.Println("Draw by repetition")
+	} else if g.IsOver() {
+		fmt.Println("Winner:", g.Winner())
 	}
-
-fail:
-	return Action{}, parseError
 }
 
-func parseShip(s string) (homeworlds.Piece, error) {
-	var pieces = map[string]homeworlds.Piece{
-		"R1": homeworlds.R1, "R2": homeworlds.R2, "R3": homeworlds.R3,
-		"Y1": homeworlds.Y1, "Y2": homeworlds.Y2, "Y3": homeworlds.Y3,
-		"G1": homeworlds.G1, "G2": homeworlds.G2, "G3": homeworlds.G3,
-		"B1": homeworlds.B1, "B2": homeworlds.B2, "B3": homeworlds.B3,
-	}
-	p, ok := pieces[s]
-	if !ok {
-		return homeworlds.Piece(0), parseError
-	}
-	return p, nil
+// seatHeader returns the record header which saves who plays pl,
+// in the form accepted by parseSeat.
+// The North and South headers are left for the players' names.
+func seatHeader(pl homeworlds.Player) string {
+	return pl.String() + "Seat"
 }
 
-func newGame() *homeworlds.Game {
-	north := &homeworlds.Star{
-		Name:        "north",
-		IsHomeworld: true,
-		Pieces:      []homeworlds.Piece{homeworlds.G3, homeworlds.Y1},
-		Ships: map[homeworlds.Player][]homeworlds.Piece{
-			homeworlds.North: {homeworlds.B3},
-		},
+// resume applies the settings saved in a record's headers
+// to the seats and the repetition policy,
+// unless they were given on the command line.
+func resume(headers map[string]string, set map[string]bool, seats [2]*string, repetition *homeworlds.RepetitionPolicy) error {
+	for i, seat := range seats {
+		pl := homeworlds.Player(i)
+		if v := headers[seatHeader(pl)]; v != "" && !set[strings.ToLower(pl.String())] {
+			*seat = v
+		}
 	}
-	south := &homeworlds.Star{
-		Name:        "south",
-		IsHomeworld: true,
-		Pieces:      []homeworlds.Piece{homeworlds.Y3, homeworlds.B2},
-		Ships: map[homeworlds.Player][]homeworlds.Piece{
-			homeworlds.South: {homeworlds.G3},
-		},
+	if v := headers["Repetition"]; v != "" && !set["repetition"] {
+		if err := repetition.Set(v); err != nil {
+			return fmt.Errorf("Repetition: %v", err)
+		}
 	}
-	game := &homeworlds.Game{
-		Phase:         0,
-		NumPlayers:    2,
-		CurrentPlayer: homeworlds.North,
-		Bank:          make(map[homeworlds.Piece]int),
-		Homeworlds: map[homeworlds.Player]string{
-			homeworlds.North: "north",
-			homeworlds.South: "south",
-		},
-		Stars: map[string]*homeworlds.Star{
-			"north": north,
-			"south": south,
-		},
+	return nil
+}
+
+// seatSpec formats a seat in the form accepted by parseSeat.
+func seatSpec(ai *homeworlds.AI) string {
+	if ai == nil {
+		return "human"
 	}
-	game.ResetBank()
-	return game
+	return "ai:" + ai.Config().String()
 }
 
-func do(g *homeworlds.Game, a Action) error {
-	star, ok := g.Stars[a.System]
-	if !ok {
-		return fmt.Errorf("no such system %s", a.System)
-	}
-	switch a.Type {
-	case homeworlds.Build:
-		return g.Build(a.Ship, star)
-	case homeworlds.Trade:
-		return g.Trade(a.Ship, star, a.NewShip)
-	case homeworlds.Move:
-		toStar, ok := g.Stars[a.NewSystem]
-		if !ok {
-			return fmt.Errorf("no such system %s", a.NewSystem)
+// parseSeat parses a seat specification:
+// "human", or "ai" optionally followed by a colon
+// and a comma-separated list of AI options which override cfg.
+// It returns nil for a human.
+func parseSeat(spec string, cfg homeworlds.AIConfig) (*homeworlds.AI, error) {
+	kind, opts := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, opts = spec[:i], spec[i+1:]
+	}
+	switch kind {
+	case "human":
+		if opts != "" {
+			return nil, fmt.Errorf("seat %q: humans have no options", spec)
 		}
-		return g.Move(a.Ship, star, toStar)
-	case homeworlds.Attack:
-		target := homeworlds.North
-		if g.CurrentPlayer == homeworlds.North {
-			target = homeworlds.South
+		return nil, nil
+	case "ai":
+		cfg, err := homeworlds.ParseAIOptions(cfg, opts)
+		if err != nil {
+			return nil, fmt.Errorf("seat %q: %v", spec, err)
 		}
-		return g.Attack(a.Ship, star, target)
-	case homeworlds.Discover:
-		return g.Discover(a.Ship, star, a.NewShip, a.NewSystem)
+		return homeworlds.NewAIWithConfig(cfg)
 	}
-	return nil
+	return nil, fmt.Errorf("seat %q: want human or ai", spec)
+}
+
+// isTerminal reports whether f is a terminal,
+// which can show colors.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magical/homeworlds"
+)
+
+func TestLoadNamedPlayers(t *testing.T) {
+	// North and South name the players, as in tournament records.
+	// Only the seat headers say who plays.
+	const record = `[North "alice"]
+[South "bob"]
+[SouthSeat "ai:depth=1"]
+[Repetition "forbid"]
+
+homeworld R2 B1 G3 North
+homeworld Y3 B2 G3 South
+`
+	dir, err := ioutil.TempDir("", "play")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "game.txt")
+	if err := ioutil.WriteFile(filename, []byte(record), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	g, headers, err := loadGame(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Ply() != 2 {
+		t.Errorf("loaded ply %d, want 2", g.Ply())
+	}
+	north, south := "human", "human"
+	var repetition homeworlds.RepetitionPolicy
+	if err := resume(headers, map[string]bool{}, [2]*string{&north, &south}, &repetition); err != nil {
+		t.Fatal(err)
+	}
+	if north != "human" || south != "ai:depth=1" || repetition != homeworlds.ForbidRepetition {
+		t.Errorf("resumed north=%q south=%q repetition=%v", north, south, repetition)
+	}
+	for _, spec := range []string{north, south} {
+		if _, err := parseSeat(spec, homeworlds.DefaultAIConfig()); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Flags given on the command line win.
+	south = "human"
+	if err := resume(headers, map[string]bool{"south": true}, [2]*string{&north, &south}, &repetition); err != nil {
+		t.Fatal(err)
+	}
+	if south != "human" {
+		t.Errorf("resumed south=%q, want the flag's value, human", south)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/magical/homeworlds"
+)
+
+// A repl reads commands from the user and plays the game.
+type repl struct {
+	g          *homeworlds.Game
+	ai         *homeworlds.AI // gives hints
+	seats      [2]*homeworlds.AI
+	printBoard func(io.Writer, *homeworlds.Game) error
+	out        io.Writer
+	quit       bool
+
+	// headers are added to saved records,
+	// to remember who is playing and under which rules.
+	headers map[string]string
+	// saveFile, if set, is rewritten after every turn.
+	saveFile string
+	// remote is set when playing against an opponent on a server.
+	remote *client
+}
+
+// A command is something the user can type at the prompt,
+// other than an action.
+type command struct {
+	usage string
+	help  string
+	run   func(r *repl, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":  {"help [command]", "list the commands, or explain one", (*repl).help},
+		"show":  {"show", "show the board", (*repl).show},
+		"moves": {"moves", "list the basic actions available", (*repl).moves},
+		"hint":  {"hint", "ask the AI for a suggestion", (*repl).hint},
+		"undo":  {"undo", "take back the last turn, and any AI turns after it", (*repl).undo},
+		"redo":  {"redo", "replay the turns which undo took back", (*repl).redo},
+		"save":  {"save <file>", "save the game record to a file", (*repl).save},
+		"load":  {"load <file>", "load a game record or position from a file", (*repl).load},
+		"quit":  {"quit", "stop playing", (*repl).exit},
+	}
+}
+
+// actionUsage describes each action, in the notation read by ParseTurn.
+var actionUsage = map[string]string{
+	"homeworld":   "homeworld <star> <star> <ship> [name]",
+	"discover":    "discover <ship> <from> <star> <name>",
+	"move":        "move <ship> <from> <to>",
+	"build":       "build <ship> <system>",
+	"trade":       "trade <ship> <new ship> <system>",
+	"attack":      "attack <ship> <system>",
+	"sacrifice":   "sacrifice <ship> <system>; <action>; ...",
+	"catastrophe": "catastrophe <color> <system>",
+	"pass":        "pass",
+}
+
+func (r *repl) run(lr *lineReader) error {
+	for !r.g.IsOver() && !r.quit {
+		if r.remote != nil && r.g.CurrentPlayer != r.remote.player {
+			if err := r.wait(); err != nil {
+				return err
+			}
+			continue
+		}
+		if seat := r.seats[r.g.CurrentPlayer]; seat != nil {
+			if err := r.aiMove(seat); err != nil {
+				return err
+			}
+			r.printBoard(r.out, r.g)
+			continue
+		}
+		line, err := lr.ReadLine(fmt.Sprintf("%s> ", r.g.CurrentPlayer))
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.exec(line); err != nil {
+			fmt.Fprintln(r.out, err)
+		}
+	}
+	return nil
+}
+
+// exec runs a command or plays a turn.
+func (r *repl) exec(line string) error {
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return nil
+	}
+	if c, ok := commands[strings.ToLower(f[0])]; ok {
+		return c.run(r, f[1:])
+	}
+	t, err := homeworlds.ParseTurn(line)
+	if err != nil {
+		// Find the command which failed to show how to use it.
+		for _, part := range strings.Split(line, ";") {
+			f := strings.Fields(part)
+			if _, err := homeworlds.ParseTurn(part); err != nil && len(f) > 0 {
+				if usage, ok := actionUsage[strings.ToLower(f[0])]; ok {
+					return fmt.Errorf("%v\nusage: %s", err, usage)
+				}
+				return fmt.Errorf("%v (type help for a list of commands)", err)
+			}
+		}
+		return err
+	}
+	r.fixNames(t)
+	fmt.Fprintln(r.out, t)
+	if err := r.play(t); err != nil {
+		return err
+	}
+	return r.printBoard(r.out, r.g)
+}
+
+// play plays a turn and saves the game.
+// In a network game, the turn is sent to the server to be checked.
+func (r *repl) play(t homeworlds.Turn) error {
+	if r.remote == nil {
+		if err := r.g.Play(t); err != nil {
+			return err
+		}
+		return r.autosave()
+	}
+	if err := r.remote.send(t); err != nil {
+		return err
+	}
+	m, err := r.remote.recv()
+	if err != nil {
+		return err
+	}
+	return r.receive(m)
+}
+
+// wait waits for the opponent to play their turn on the server.
+func (r *repl) wait() error {
+	m, err := r.remote.recv()
+	if err != nil {
+		return err
+	}
+	if m.Type == "turn" {
+		fmt.Fprintf(r.out, "%s plays: %s\n", r.g.CurrentPlayer, m.Turn)
+	}
+	if err := r.receive(m); err != nil {
+		return err
+	}
+	if m.Type == "turn" {
+		return r.printBoard(r.out, r.g)
+	}
+	return nil
+}
+
+// receive handles a message from the server.
+func (r *repl) receive(m homeworlds.Message) error {
+	switch m.Type {
+	case "turn":
+		if err := r.g.Play(m.Turn); err != nil {
+			// The server knows best.
+			if m.Game == nil {
+				return err
+			}
+			r.g = m.Game
+		}
+		return r.autosave()
+	case "end":
+		fmt.Fprintln(r.out, m.Result)
+		r.quit = true
+		return nil
+	case "error":
+		return errors.New(m.Error)
+	}
+	return fmt.Errorf("unexpected message %q from server", m.Type)
+}
+
+// fixNames corrects the case of the names of existing stars
+// and names homeworlds after their owner if no name was given.
+func (r *repl) fixNames(t homeworlds.Turn) {
+	fix := func(name *string) {
+		if _, ok := r.g.Stars[*name]; ok {
+			return
+		}
+		for s := range r.g.Stars {
+			if strings.EqualFold(s, *name) {
+				*name = s
+			}
+		}
+	}
+	for i := range t {
+		switch t[i].Type {
+		case homeworlds.Homeworld:
+			if t[i].System == "" {
+				t[i].System = r.g.CurrentPlayer.String()
+			}
+		case homeworlds.Discover:
+			fix(&t[i].System)
+		default:
+			fix(&t[i].System)
+			fix(&t[i].NewSystem)
+		}
+	}
+}
+
+// completions returns the words which can be completed at the prompt.
+func (r *repl) completions() []string {
+	var words []string
+	for name := range commands {
+		words = append(words, name)
+	}
+	for name := range actionUsage {
+		words = append(words, name)
+	}
+	for name := range r.g.Stars {
+		words = append(words, name)
+	}
+	return append(words, "home")
+}
+
+func (r *repl) help(args []string) error {
+	if len(args) > 0 {
+		name := strings.ToLower(args[0])
+		if c, ok := commands[name]; ok {
+			fmt.Fprintf(r.out, "usage: %s\n  %s\n", c.usage, c.help)
+			return nil
+		}
+		if usage, ok := actionUsage[name]; ok {
+			fmt.Fprintf(r.out, "usage: %s\n", usage)
+			return nil
+		}
+		return fmt.Errorf("help: no such command %q", args[0])
+	}
+	fmt.Fprintln(r.out, "Actions (separate the actions of a sacrifice with ';'):")
+	for _, name := range sortedKeys(actionUsage) {
+		fmt.Fprintf(r.out, "  %s\n", actionUsage[name])
+	}
+	fmt.Fprintln(r.out, "Commands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(r.out, "  %-16s %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintln(r.out, "Pieces are written like G3 or b1. Press tab to complete the name of a star.")
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *repl) show(args []string) error {
+	return r.printBoard(r.out, r.g)
+}
+
+func (r *repl) moves(args []string) error {
+	if r.g.Homeworld(r.g.CurrentPlayer) == nil {
+		fmt.Fprintln(r.out, "Choose a homeworld:", actionUsage["homeworld"])
+		return nil
+	}
+	for i, b := range r.g.BasicActions() {
+		fmt.Fprintf(r.out, "%3d. %s\n", i+1, r.g.FormatAction(b.Action()))
+	}
+	if n := len(r.g.SacrificeActions()); n > 0 {
+		fmt.Fprintf(r.out, "and %d ways to sacrifice a ship\n", n)
+	}
+	return nil
+}
+
+func (r *repl) hint(args []string) error {
+	t, v, err := think(r.g, r.ai)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "Hint: %s (score %.3f)\n", r.g.FormatTurn(t), v)
+	return nil
+}
+
+func (r *repl) undo(args []string) error {
+	if r.remote != nil {
+		return errors.New("undo: not allowed in a network game")
+	}
+	ply, ok := r.humanPly(-1)
+	if !ok {
+		return errors.New("undo: no turn to undo")
+	}
+	if err := r.g.JumpTo(ply); err != nil {
+		return err
+	}
+	if err := r.autosave(); err != nil {
+		return err
+	}
+	return r.printBoard(r.out, r.g)
+}
+
+func (r *repl) redo(args []string) error {
+	if r.remote != nil {
+		return errors.New("redo: not allowed in a network game")
+	}
+	ply, ok := r.humanPly(+1)
+	if !ok {
+		return errors.New("redo: no turn to redo")
+	}
+	if err := r.g.JumpTo(ply); err != nil {
+		return err
+	}
+	if err := r.autosave(); err != nil {
+		return err
+	}
+	return r.printBoard(r.out, r.g)
+}
+
+// humanPly finds the nearest ply before (dir = -1) or after (dir = +1)
+// the current one at which a human is to move,
+// so that undo and redo step over the AI's turns,
+// which the AI would otherwise play again straight away.
+// Redo stops at the end of the history regardless.
+func (r *repl) humanPly(dir int) (int, bool) {
+	states := r.g.History()
+	for ply := r.g.Ply() + dir; ply >= 0 && ply < len(states); ply += dir {
+		if r.seats[states[ply].CurrentPlayer] == nil || ply == len(states)-1 && dir > 0 {
+			return ply, true
+		}
+	}
+	return 0, false
+}
+
+func (r *repl) save(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["save"].usage)
+	}
+	return r.saveTo(args[0])
+}
+
+// autosave saves the game to the save file, if there is one.
+func (r *repl) autosave() error {
+	if r.saveFile == "" {
+		return nil
+	}
+	return r.saveTo(r.saveFile)
+}
+
+// saveTo writes the game record and the repl's headers to a file.
+// The record is written to a temporary file first
+// so that an interrupted save doesn't lose the game.
+func (r *repl) saveTo(filename string) error {
+	rec, err := r.g.Record()
+	if err != nil {
+		return err
+	}
+	for k, v := range r.headers {
+		if rec.Headers == nil {
+			rec.Headers = make(map[string]string)
+		}
+		rec.Headers[k] = v
+	}
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := homeworlds.WriteRecord(f, rec); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func (r *repl) load(args []string) error {
+	if r.remote != nil {
+		return errors.New("load: not allowed in a network game")
+	}
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["load"].usage)
+	}
+	g, _, err := loadGame(args[0])
+	if err != nil {
+		return err
+	}
+	g.Repetition = r.g.Repetition
+	r.g = g
+	if err := r.autosave(); err != nil {
+		return err
+	}
+	return r.printBoard(r.out, r.g)
+}
+
+// loadGame reads a game record or a position in FEN from a file.
+// It returns the game and the record's headers.
+func loadGame(filename string) (*homeworlds.Game, map[string]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec, err := homeworlds.ReadRecord(bytes.NewReader(data))
+	if err != nil {
+		g, ferr := homeworlds.ParseFEN(strings.TrimSpace(string(data)))
+		if ferr != nil {
+			return nil, nil, fmt.Errorf("%s: not a game record (%v) or a position (%v)", filename, err, ferr)
+		}
+		return g, nil, nil
+	}
+	g, err := rec.Replay()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return g, rec.Headers, nil
+}
+
+func (r *repl) exit(args []string) error {
+	r.quit = true
+	return nil
+}
+
+// aiMove plays the AI's turn and shows what it did.
+func (r *repl) aiMove(ai *homeworlds.AI) error {
+	t, v, err := think(r.g, ai)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "%s plays: %s (score %.3f)\n", r.g.CurrentPlayer, r.g.FormatTurn(t), v)
+	return r.play(t)
+}
+
+// think asks the AI for the current player's turn.
+// During the setup phase, it chooses a homeworld.
+func think(g *homeworlds.Game, ai *homeworlds.AI) (homeworlds.Turn, float64, error) {
+	if g.Homeworld(g.CurrentPlayer) == nil {
+		t, err := ai.ChooseHomeworld(g)
+		return t, 0, err
+	}
+	ai.SetHistory(g)
+	a, v := ai.Search(homeworlds.PositionFromGame(g), homeworlds.BasicAction{})
+	t, err := g.ActionTurn(a)
+	return t, v, err
+}
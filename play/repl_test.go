@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/magical/homeworlds"
+)
+
+func newTestRepl(seats [2]*homeworlds.AI, out *bytes.Buffer) *repl {
+	return &repl{
+		g:          homeworlds.NewGame(2),
+		ai:         homeworlds.NewAI(),
+		seats:      seats,
+		printBoard: homeworlds.Print,
+		out:        out,
+	}
+}
+
+// runInput runs the repl with the given lines as input.
+func runInput(r *repl, input string) error {
+	lr := &lineReader{in: bufio.NewReader(strings.NewReader(input)), out: r.out}
+	return r.run(lr)
+}
+
+func TestUndoAI(t *testing.T) {
+	ai := homeworlds.NewAI()
+
+	// North sets up, South's AI replies,
+	// and undo takes back both turns.
+	var out bytes.Buffer
+	r := newTestRepl([2]*homeworlds.AI{nil, ai}, &out)
+	if err := runInput(r, "homeworld R1 B2 G3\nundo\n"); err != nil {
+		t.Fatal(err)
+	}
+	if r.g.Ply() != 0 {
+		t.Fatalf("after undo, ply = %d, want 0:\n%s", r.g.Ply(), out.String())
+	}
+	if err := r.exec("redo"); err != nil {
+		t.Fatal(err)
+	}
+	if r.g.Ply() != 2 || r.g.CurrentPlayer != homeworlds.North {
+		t.Errorf("after redo, ply = %d with %s to move, want 2 with North to move", r.g.Ply(), r.g.CurrentPlayer)
+	}
+
+	// When the AI moves first, there is no human turn to take back.
+	out.Reset()
+	r = newTestRepl([2]*homeworlds.AI{ai, nil}, &out)
+	if err := runInput(r, "undo\n"); err != nil {
+		t.Fatal(err)
+	}
+	if r.g.Ply() != 1 || !strings.Contains(out.String(), "no turn to undo") {
+		t.Errorf("undo before South's first turn: ply = %d, want 1:\n%s", r.g.Ply(), out.String())
+	}
+}
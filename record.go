@@ -0,0 +1,497 @@
+package homeworlds
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Command is a single action as written in a game record.
+// Unlike Action, which refers to systems by their index in a Position,
+// a Command refers to systems by name.
+type Command struct {
+	Type ActionType
+
+	// Ship is the ship which takes the action,
+	// or for Attack, the ship being attacked.
+	Ship Piece
+
+	// NewShip is the piece a ship is traded for,
+	// or for Discover, the piece the new star is made of.
+	NewShip Piece
+
+	// Star holds the two pieces of a new homeworld.
+	Star [2]Piece
+
+	// System is the name of the system where the action takes place,
+	// or for Homeworld, the name of the new homeworld.
+	System string
+
+	// NewSystem is the name of the system a ship moves to,
+	// or the name of a newly discovered system.
+	NewSystem string
+
+	// Color is the color of a catastrophe.
+	Color Color
+}
+
+// A Turn is the list of commands taken by a player in one turn.
+//
+// A turn consists of a single action,
+// or a sacrifice followed by up to as many actions as the size of the sacrificed ship,
+// or a pass.
+// Catastrophes may be triggered at any point.
+type Turn []Command
+
+// A Record is a list of turns which make up a game,
+// starting from an empty board,
+// or from the position in its "Position" header, if it has one.
+type Record struct {
+	// Headers holds information about the game,
+	// such as the names of the players.
+	Headers map[string]string
+
+	Turns []Turn
+}
+
+// String formats the command as text.
+// The output looks like this:
+//
+//	homeworld R2 B1 G3 north
+//	discover G1 north Y2 alpha
+//	move G1 alpha beta
+//	build G1 alpha
+//	trade G1 B1 alpha
+//	attack R1 alpha
+//	sacrifice Y3 north
+//	catastrophe red alpha
+//	pass
+func (c Command) String() string {
+	switch c.Type {
+	case Homeworld:
+		return fmt.Sprintf("homeworld %s %s %s %s", c.Star[0], c.Star[1], c.Ship, c.System)
+	case Discover:
+		return fmt.Sprintf("discover %s %s %s %s", c.Ship, c.System, c.NewShip, c.NewSystem)
+	case Move:
+		return fmt.Sprintf("move %s %s %s", c.Ship, c.System, c.NewSystem)
+	case Build:
+		return fmt.Sprintf("build %s %s", c.Ship, c.System)
+	case Trade:
+		return fmt.Sprintf("trade %s %s %s", c.Ship, c.NewShip, c.System)
+	case Attack:
+		return fmt.Sprintf("attack %s %s", c.Ship, c.System)
+	case Sacrifice:
+		return fmt.Sprintf("sacrifice %s %s", c.Ship, c.System)
+	case Catastrope:
+		return fmt.Sprintf("catastrophe %s %s", strings.ToLower(c.Color.String()), c.System)
+	case Pass:
+		return "pass"
+	}
+	return fmt.Sprintf("unknown command %d [BUG]", c.Type)
+}
+
+// String formats the turn as a list of commands separated by semicolons.
+func (t Turn) String() string {
+	if len(t) == 0 {
+		return "pass"
+	}
+	s := make([]string, len(t))
+	for i, c := range t {
+		s[i] = c.String()
+	}
+	return strings.Join(s, "; ")
+}
+
+// ParsePiece parses a piece name such as "G3" or "g3".
+func ParsePiece(s string) (Piece, error) {
+	if len(s) == 2 {
+		c, err := ParseColor(s[:1])
+		if err == nil && '1' <= s[1] && s[1] <= '3' {
+			return piece(Size(s[1]-'0'), c), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid piece %q", s)
+}
+
+// ParseColor parses a color name such as "green", "Green", or "g".
+func ParseColor(s string) (Color, error) {
+	switch strings.ToLower(s) {
+	case "r", "red":
+		return Red, nil
+	case "y", "yellow":
+		return Yellow, nil
+	case "g", "green":
+		return Green, nil
+	case "b", "blue":
+		return Blue, nil
+	}
+	return 0, fmt.Errorf("invalid color %q", s)
+}
+
+// power returns the color of the power a command uses,
+// and false if it doesn't use one.
+func (c Command) power() (Color, bool) {
+	switch c.Type {
+	case Attack:
+		return Red, true
+	case Move, Discover:
+		return Yellow, true
+	case Build:
+		return Green, true
+	case Trade:
+		return Blue, true
+	}
+	return 0, false
+}
+
+// Play takes a turn for the current player:
+// it performs each command in order and then ends the turn.
+//
+// Returns an error if any command fails
+// or if the turn as a whole breaks the rules,
+// in which case the game is left unchanged.
+func (g *Game) Play(t Turn) error {
+	next, err := g.next(t)
+	if err != nil {
+		return err
+	}
+	g.setState(next)
+	g.record(t)
+	return nil
+}
+
+// next plays t on a copy of the current state
+// and returns the state at the end of the turn,
+// without changing the game or its history.
+func (g *Game) next(t Turn) (*Game, error) {
+	g.begin()
+	tmp := g.snapshot()
+	// share the history, so that tmp can check for repetition
+	tmp.history, tmp.seen = g.history, g.seen
+	if err := tmp.play(t); err != nil {
+		return nil, err
+	}
+	if err := tmp.CheckRepetition(); err != nil {
+		return nil, err
+	}
+	tmp.history, tmp.seen = nil, nil
+	tmp.CurrentPlayer = Player((int(tmp.CurrentPlayer) + 1) % tmp.NumPlayers)
+	return tmp, nil
+}
+
+// Apply plays an action chosen for PositionFromGame(g),
+// such as the AI's move, as the current player's turn.
+//
+// The action is performed along with any follow-up actions of a sacrifice
+// and any catastrophes they cause,
+// and then the turn passes to the next player.
+// Returns an error if the action cannot be played,
+// or if the result differs from what the action does to the position,
+// in which case the game is left unchanged.
+func (g *Game) Apply(a Action) error {
+	pos := PositionFromGame(g)
+	t, err := actionTurn(pos, g.StarNames(), a, g.newStarName())
+	if err != nil {
+		return fmt.Errorf("Apply: %v", err)
+	}
+	next, err := g.next(t)
+	if err != nil {
+		return err
+	}
+	pos = pos.Do(a)
+	// A Position keeps the pieces of an abandoned homeworld,
+	// but a Game destroys the star, so only compare unfinished games.
+	if !next.IsOver() && PositionFromGame(next).key() != pos.key() {
+		return fmt.Errorf("Apply: %s did not have the effect of %s", t, a.Basic())
+	}
+	g.setState(next)
+	g.record(t)
+	return nil
+}
+
+func (g *Game) play(t Turn) error {
+	var (
+		n          = 0     // number of actions
+		allowed    = 1     // number of actions allowed
+		sacrificed = false // whether the turn began with a sacrifice
+		color      Color   // color of the sacrificed ship
+	)
+	for _, c := range t {
+		if c.Type == Catastrope {
+			if err := g.do(c); err != nil {
+				return err
+			}
+			continue
+		}
+		if n >= allowed {
+			return errors.New("Play: too many actions")
+		}
+		n++
+		switch c.Type {
+		case Pass:
+			// nothing to do
+		case Homeworld:
+			// checked by BuildHomeworld
+		case Sacrifice:
+			if n != 1 {
+				return errors.New("Sacrifice: must be the first action of the turn")
+			}
+			sacrificed = true
+			color = c.Ship.Color()
+			allowed = 1 + int(c.Ship.Size())
+		default:
+			if g.Homeworld(g.CurrentPlayer) == nil {
+				return fmt.Errorf("%s: player has no homeworld", c.Type)
+			}
+			p, _ := c.power()
+			if sacrificed {
+				if p != color {
+					return fmt.Errorf("%s: not allowed after sacrificing a %s ship", c.Type, strings.ToLower(color.String()))
+				}
+			} else {
+				s, err := g.star(c.System)
+				if err != nil {
+					return err
+				}
+				if !s.hasPower(g.CurrentPlayer, p) {
+					return fmt.Errorf("%s: %s power not available at %s", c.Type, strings.ToLower(p.String()), s.Name)
+				}
+			}
+		}
+		if err := g.do(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasPower reports whether the player has access to a color's power at the star:
+// whether the star or one of the player's ships there is of that color.
+func (s *Star) hasPower(pl Player, c Color) bool {
+	if len(s.Ships[pl]) == 0 {
+		return false
+	}
+	for _, p := range s.Pieces {
+		if p.Color() == c {
+			return true
+		}
+	}
+	return s.ownsColor(pl, c)
+}
+
+// star looks up a star by name.
+// If there is no such star, the name "home" refers to the current player's homeworld.
+func (g *Game) star(name string) (*Star, error) {
+	if s, ok := g.Stars[name]; ok {
+		return s, nil
+	}
+	if name == "home" {
+		if s := g.Homeworld(g.CurrentPlayer); s != nil {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no such system %s", name)
+}
+
+// do performs a single command, without checking
+// whether the player has access to the required power.
+func (g *Game) do(c Command) error {
+	switch c.Type {
+	case Pass:
+		return nil
+	case Homeworld:
+		return g.BuildHomeworld(c.Star[0], c.Star[1], c.Ship, c.System)
+	}
+	s, err := g.star(c.System)
+	if err != nil {
+		return err
+	}
+	switch c.Type {
+	case Build:
+		return g.Build(c.Ship, s)
+	case Trade:
+		return g.Trade(c.Ship, s, c.NewShip)
+	case Move:
+		dst, err := g.star(c.NewSystem)
+		if err != nil {
+			return err
+		}
+		return g.Move(c.Ship, s, dst)
+	case Discover:
+		return g.Discover(c.Ship, s, c.NewShip, c.NewSystem)
+	case Attack:
+		for pl := Player(0); int(pl) < g.NumPlayers; pl++ {
+			if pl != g.CurrentPlayer && s.owns(pl, c.Ship) {
+				return g.Attack(c.Ship, s, pl)
+			}
+		}
+		return errors.New("Attack: no such piece")
+	case Sacrifice:
+		return g.Sacrifice(c.Ship, s)
+	case Catastrope:
+		return g.Catastrophe(c.Color, s)
+	}
+	return fmt.Errorf("unknown command %d", c.Type)
+}
+
+// Replay plays the turns of the record, starting from a new game
+// or the record's starting position,
+// and returns the resulting game.
+// If a turn cannot be played, Replay returns the game
+// as it was before that turn and an error.
+func (r *Record) Replay() (*Game, error) {
+	g := NewGame(2)
+	if s := r.Headers["Position"]; s != "" {
+		var err error
+		if g, err = ParseFEN(s); err != nil {
+			return nil, fmt.Errorf("Position: %v", err)
+		}
+	}
+	for i, t := range r.Turns {
+		if err := g.Play(t); err != nil {
+			return g, fmt.Errorf("turn %d: %v", i+1, err)
+		}
+	}
+	return g, nil
+}
+
+// Record returns the turns taken over the course of the game,
+// up to the current ply.
+// If the history doesn't start from an empty board,
+// the starting position is recorded in the "Position" header.
+// Returns an error if a turn was ended with EndTurn instead of Play.
+func (g *Game) Record() (*Record, error) {
+	states := g.states()
+	if states == nil {
+		states = []*state{{game: g}}
+	}
+	r := new(Record)
+	if start := states[0].game; len(start.Stars) != 0 || start.CurrentPlayer != North {
+		r.Headers = map[string]string{"Position": start.FEN()}
+	}
+	for i, s := range states[1:] {
+		if s.turn == nil {
+			return nil, fmt.Errorf("Record: turn %d was not recorded", i+1)
+		}
+		r.Turns = append(r.Turns, s.turn)
+	}
+	return r, nil
+}
+
+// parseCommand parses the fields of a single command.
+// Piece and color names are case-insensitive; system names are not.
+// The color and system of a catastrophe may be given in either order.
+// If there is an error, parseCommand also returns the index
+// of the field responsible.
+func parseCommand(f []string) (Command, int, error) {
+	var c Command
+	if len(f) == 0 {
+		return c, 0, errors.New("missing command")
+	}
+	verb := strings.ToLower(f[0])
+	nargs := map[string][]int{
+		"homeworld":   {3, 4},
+		"discover":    {4},
+		"move":        {3},
+		"build":       {2},
+		"trade":       {3},
+		"attack":      {2},
+		"sacrifice":   {2},
+		"catastrophe": {2},
+		"pass":        {0},
+	}
+	n, ok := nargs[verb]
+	if !ok {
+		return c, 0, fmt.Errorf("unknown command %q", f[0])
+	}
+	if len(f)-1 != n[0] && (len(n) == 1 || len(f)-1 != n[1]) {
+		return c, 0, fmt.Errorf("%s: wrong number of arguments", verb)
+	}
+	var err error
+	bad := 0
+	piece := func(p *Piece, i int) {
+		if err == nil {
+			*p, err = ParsePiece(f[i])
+			bad = i
+		}
+	}
+	switch verb {
+	case "homeworld":
+		c.Type = Homeworld
+		piece(&c.Star[0], 1)
+		piece(&c.Star[1], 2)
+		piece(&c.Ship, 3)
+		if len(f) > 4 {
+			c.System = f[4]
+		}
+	case "discover":
+		c.Type = Discover
+		c.System, c.NewSystem = f[2], f[4]
+		piece(&c.Ship, 1)
+		piece(&c.NewShip, 3)
+	case "move":
+		c.Type = Move
+		c.System, c.NewSystem = f[2], f[3]
+		piece(&c.Ship, 1)
+	case "build":
+		c.Type = Build
+		c.System = f[2]
+		piece(&c.Ship, 1)
+	case "trade":
+		c.Type = Trade
+		c.System = f[3]
+		piece(&c.Ship, 1)
+		piece(&c.NewShip, 2)
+	case "attack":
+		c.Type = Attack
+		c.System = f[2]
+		piece(&c.Ship, 1)
+	case "sacrifice":
+		c.Type = Sacrifice
+		c.System = f[2]
+		piece(&c.Ship, 1)
+	case "catastrophe":
+		c.Type = Catastrope
+		var col Color
+		bad = 1
+		if col, err = ParseColor(f[1]); err == nil {
+			c.Color, c.System = col, f[2]
+		} else if col, err = ParseColor(f[2]); err == nil {
+			c.Color, c.System = col, f[1]
+		}
+	case "pass":
+		c.Type = Pass
+	}
+	if err != nil {
+		return c, bad, fmt.Errorf("%s: %v", verb, err)
+	}
+	return c, 0, nil
+}
+
+// ParseTurn parses a list of commands separated by semicolons,
+// such as "sacrifice Y2 north; move G1 north alpha; move G3 north alpha".
+// The commands are described in Command.String,
+// but piece and color names are case-insensitive.
+func ParseTurn(s string) (Turn, error) {
+	var t Turn
+	for _, part := range strings.Split(s, ";") {
+		c, _, err := parseCommand(strings.Fields(part))
+		if err != nil {
+			return nil, err
+		}
+		t = append(t, c)
+	}
+	return t, nil
+}
+
+// nameHomeworlds gives unnamed homeworlds the name of the player who built them.
+func (rec *Record) nameHomeworlds() {
+	for i, t := range rec.Turns {
+		pl := Player(i % 2)
+		for j := range t {
+			if t[j].Type == Homeworld && t[j].System == "" {
+				t[j].System = pl.String()
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package homeworlds
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	sacrifice := mksacrifice(Y2, 2).append(mkmove(G1, 2, 3))
+	tests := []struct {
+		fen    string
+		action Action
+		turn   string
+		want   string
+	}{
+		{
+			"333/232/121/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=G2:G1G1:- N",
+			mkbasic(Build, G1, 2, 0).Action(),
+			"build G1 alpha; catastrophe green alpha",
+			"333/232/331/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 S",
+		},
+		{
+			"332/232/231/222 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=B1:R3:G1 N",
+			mkbasic(Attack, G1, 2, 0).Action(),
+			"attack G1 alpha",
+			"332/232/231/222 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=B1:R3G1:- S",
+		},
+		{
+			"233/222/021/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 alpha=R1:Y2G1:- beta=G2:-:G1G1 N",
+			sacrifice,
+			"sacrifice Y2 alpha; move G1 alpha beta; catastrophe green beta",
+			"333/232/331/322 north@N=Y1G3:B3:- south@S=Y3B2:-:G3 S",
+		},
+	}
+	for _, tt := range tests {
+		g, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		turn, err := g.ActionTurn(tt.action)
+		if err != nil {
+			t.Errorf("%s: %v", tt.fen, err)
+			continue
+		}
+		if turn.String() != tt.turn {
+			t.Errorf("%s: ActionTurn = %s, want %s", tt.fen, turn, tt.turn)
+		}
+		if err := g.Apply(tt.action); err != nil {
+			t.Errorf("%s: Apply: %v", tt.fen, err)
+			continue
+		}
+		if got := g.FEN(); got != tt.want {
+			t.Errorf("%s: after Apply got %s, want %s", tt.fen, got, tt.want)
+		}
+	}
+
+	g, err := ParseFEN(tests[0].fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Apply(mkbasic(Attack, G1, 2, 0).Action()); err == nil {
+		t.Errorf("Apply succeeded with no ship to attack")
+	}
+	if got := g.FEN(); got != tests[0].fen {
+		t.Errorf("failed Apply changed the game to %s", got)
+	}
+}
@@ -0,0 +1,344 @@
+package homeworlds
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Render draws the game as an image in the given format,
+// which is either "svg" or "png".
+//
+// Each star is drawn as one or two upright pyramids,
+// with North's ships above it pointing down
+// and South's ships below it pointing up.
+// North's homeworld is at the top, South's at the bottom,
+// and the other stars are in rows between them.
+// Lines join the stars which are connected.
+//
+// PNG images use a small bitmap font for the names of the stars.
+func Render(w io.Writer, g *Game, format string) error {
+	sc := layout(g)
+	switch format {
+	case "svg":
+		return sc.writeSVG(w)
+	case "png":
+		return png.Encode(w, sc.rasterize())
+	}
+	return fmt.Errorf("Render: unknown format %q", format)
+}
+
+type point struct{ x, y float64 }
+
+// A shape is a filled polygon with an outline.
+type shape struct {
+	points []point
+	fill   color.RGBA
+	stroke color.RGBA
+	width  float64
+}
+
+type label struct {
+	at   point
+	text string
+}
+
+// A scene is a picture made of shapes and labels.
+type scene struct {
+	width, height int
+	shapes        []shape
+	labels        []label
+	caption       string
+}
+
+var (
+	pieceColors = [...]color.RGBA{
+		Red:    {0xd8, 0x33, 0x2a, 0xff},
+		Yellow: {0xf0, 0xc0, 0x1c, 0xff},
+		Green:  {0x3a, 0xa6, 0x3f, 0xff},
+		Blue:   {0x2f, 0x6b, 0xc9, 0xff},
+	}
+	outlineColor    = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	connectionColor = color.RGBA{0xbb, 0xbb, 0xbb, 0xff}
+	backgroundColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+const (
+	cellWidth   = 180
+	cellHeight  = 260
+	starsPerRow = 4
+	shipsPerRow = 5
+)
+
+// pieceWidth returns the width of the base of a pyramid.
+func pieceWidth(s Size) float64 {
+	return float64(8 + 8*s)
+}
+
+// pyramid returns a pyramid for the piece
+// with the middle of its base at x, y,
+// pointing up, or down if down is true.
+func pyramid(p Piece, x, y float64, down bool) shape {
+	w := pieceWidth(p.Size())
+	h := w * 1.3
+	if down {
+		h = -h
+	}
+	return shape{
+		points: []point{{x - w/2, y}, {x + w/2, y}, {x, y - h}},
+		fill:   pieceColors[p.Color()],
+		stroke: outlineColor,
+		width:  1.5,
+	}
+}
+
+// line returns a line of the given width as a shape.
+func line(a, b point, width float64, c color.RGBA) shape {
+	dx, dy := b.x-a.x, b.y-a.y
+	n := math.Hypot(dx, dy)
+	if n == 0 {
+		n = 1
+	}
+	nx, ny := -dy/n*width/2, dx/n*width/2
+	return shape{
+		points: []point{{a.x + nx, a.y + ny}, {b.x + nx, b.y + ny}, {b.x - nx, b.y - ny}, {a.x - nx, a.y - ny}},
+		fill:   c,
+	}
+}
+
+// layout arranges the game's stars and ships.
+func layout(g *Game) *scene {
+	var stars []*Star
+	for _, name := range g.StarNames() {
+		if s := g.Stars[name]; s != nil {
+			stars = append(stars, s)
+		}
+	}
+	var middle []*Star
+	for _, s := range stars {
+		if !s.IsHomeworld {
+			middle = append(middle, s)
+		}
+	}
+	cols := len(middle)
+	if cols > starsPerRow {
+		cols = starsPerRow
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (len(middle) + starsPerRow - 1) / starsPerRow
+	sc := &scene{width: cols * cellWidth, height: (rows + 2) * cellHeight}
+
+	// Work out where each star goes.
+	center := make(map[*Star]point)
+	if s := g.Homeworld(North); s != nil {
+		center[s] = point{float64(sc.width) / 2, cellHeight / 2}
+	}
+	if s := g.Homeworld(South); s != nil {
+		center[s] = point{float64(sc.width) / 2, float64(sc.height) - cellHeight/2}
+	}
+	for i, s := range middle {
+		row, col := i/starsPerRow, i%starsPerRow
+		n := starsPerRow
+		if row == rows-1 && len(middle)%starsPerRow != 0 {
+			n = len(middle) % starsPerRow
+		}
+		// center the last row
+		x := (float64(col)+0.5)*cellWidth + float64(cols-n)*cellWidth/2
+		center[s] = point{x, float64(row+1)*cellHeight + cellHeight/2}
+	}
+
+	// Connections go underneath everything else.
+	for i, s := range stars {
+		for _, t := range stars[i+1:] {
+			if s.connects(t) {
+				sc.shapes = append(sc.shapes, line(center[s], center[t], 3, connectionColor))
+			}
+		}
+	}
+
+	for _, s := range stars {
+		c := center[s]
+		// The star sits in the middle of its cell.
+		x := c.x - float64(len(s.Pieces)-1)*16
+		for _, p := range s.Pieces {
+			sc.shapes = append(sc.shapes, pyramid(p, x, c.y+25, false))
+			x += 32
+		}
+		sc.shipRows(s.Ships[North], c.x, c.y-72, true)
+		sc.shipRows(s.Ships[South], c.x, c.y+78, false)
+		name := s.Name
+		if s.IsHomeworld {
+			for pl, hw := range g.Homeworlds {
+				if hw == s.Name {
+					name = pl.String() + "'s homeworld"
+				}
+			}
+		}
+		sc.labels = append(sc.labels, label{point{c.x - cellWidth/2 + 6, c.y - cellHeight/2 + 14}, name})
+	}
+	return sc
+}
+
+// shipRows adds ships in rows,
+// with the bases of the first row at y
+// and further rows moving away from the star.
+func (sc *scene) shipRows(ships []Piece, x, y float64, down bool) {
+	ships = append([]Piece(nil), ships...)
+	// largest first
+	for i := 1; i < len(ships); i++ {
+		for j := i; j > 0 && ships[j].Size() > ships[j-1].Size(); j-- {
+			ships[j], ships[j-1] = ships[j-1], ships[j]
+		}
+	}
+	dy := 45.0
+	if down {
+		dy = -dy
+	}
+	for i := 0; i < len(ships); i += shipsPerRow {
+		row := ships[i:]
+		if len(row) > shipsPerRow {
+			row = row[:shipsPerRow]
+		}
+		sx := x - float64(len(row)-1)*16
+		for _, p := range row {
+			sc.shapes = append(sc.shapes, pyramid(p, sx, y, down))
+			sx += 32
+		}
+		y += dy
+	}
+}
+
+func (sc *scene) writeSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	height := sc.totalHeight()
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", sc.width, height, sc.width, height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(backgroundColor))
+	for _, s := range sc.shapes {
+		fmt.Fprint(bw, `<polygon points="`)
+		for i, p := range s.points {
+			if i > 0 {
+				fmt.Fprint(bw, " ")
+			}
+			fmt.Fprintf(bw, "%.1f,%.1f", p.x, p.y)
+		}
+		fmt.Fprintf(bw, `" fill="%s"`, svgColor(s.fill))
+		if s.width > 0 {
+			fmt.Fprintf(bw, ` stroke="%s" stroke-width="%g" stroke-linejoin="round"`, svgColor(s.stroke), s.width)
+		}
+		fmt.Fprintln(bw, "/>")
+	}
+	for _, l := range sc.labels {
+		fmt.Fprintf(bw, `<text x="%.1f" y="%.1f" font-family="sans-serif" font-size="13">%s</text>`+"\n", l.at.x, l.at.y, html.EscapeString(l.text))
+	}
+	for i, line := range sc.captionLines() {
+		fmt.Fprintf(bw, `<text x="8" y="%d" font-family="monospace" font-size="15">%s</text>`+"\n", sc.height+(i+1)*lineHeight, html.EscapeString(line))
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// rasterize draws the scene's shapes, labels, and caption into an image.
+func (sc *scene) rasterize() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, sc.width, sc.totalHeight()))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for _, s := range sc.shapes {
+		if s.width > 0 {
+			// Draw the outline by filling a slightly larger copy of the shape first.
+			fillPolygon(img, grow(s.points, s.width), s.stroke)
+		}
+		fillPolygon(img, s.points, s.fill)
+	}
+	for _, l := range sc.labels {
+		// Labels are placed by their baseline, as in SVG,
+		// and drawn smaller if they would run into the next label
+		// or off the edge of the image.
+		room := float64(sc.width) - l.at.x
+		for _, m := range sc.labels {
+			if m.at.y == l.at.y && m.at.x > l.at.x && m.at.x-l.at.x < room {
+				room = m.at.x - l.at.x
+			}
+		}
+		scale := textScale
+		if float64(len(l.text)*6*scale) > room-6 {
+			scale = 1
+		}
+		drawText(img, l.text, int(l.at.x), int(l.at.y)-7*scale, scale, outlineColor)
+	}
+	for i, line := range sc.captionLines() {
+		drawText(img, line, 8, sc.height+6+i*lineHeight, textScale, outlineColor)
+	}
+	return img
+}
+
+// grow moves each point of a convex polygon away from its center by d.
+func grow(pts []point, d float64) []point {
+	var c point
+	for _, p := range pts {
+		c.x += p.x
+		c.y += p.y
+	}
+	c.x /= float64(len(pts))
+	c.y /= float64(len(pts))
+	out := make([]point, len(pts))
+	for i, p := range pts {
+		dx, dy := p.x-c.x, p.y-c.y
+		n := math.Hypot(dx, dy)
+		if n == 0 {
+			out[i] = p
+			continue
+		}
+		out[i] = point{p.x + dx/n*d, p.y + dy/n*d}
+	}
+	return out
+}
+
+// fillPolygon fills a polygon using the even-odd rule,
+// sampling each pixel at its center.
+func fillPolygon(img *image.RGBA, pts []point, c color.RGBA) {
+	b := img.Bounds()
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range pts {
+		minY = math.Min(minY, p.y)
+		maxY = math.Max(maxY, p.y)
+	}
+	y0 := int(math.Max(math.Floor(minY), float64(b.Min.Y)))
+	y1 := int(math.Min(math.Ceil(maxY), float64(b.Max.Y)))
+	var xs []float64
+	for y := y0; y < y1; y++ {
+		fy := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			p, q := pts[i], pts[(i+1)%len(pts)]
+			if (p.y <= fy) == (q.y <= fy) {
+				continue
+			}
+			xs = append(xs, p.x+(fy-p.y)*(q.x-p.x)/(q.y-p.y))
+		}
+		// insertion sort; polygons here have few edges
+		for i := 1; i < len(xs); i++ {
+			for j := i; j > 0 && xs[j] < xs[j-1]; j-- {
+				xs[j], xs[j-1] = xs[j-1], xs[j]
+			}
+		}
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := int(math.Max(math.Ceil(xs[i]-0.5), float64(b.Min.X)))
+			x1 := int(math.Min(math.Ceil(xs[i+1]-0.5), float64(b.Max.X)))
+			for x := x0; x < x1; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package homeworlds
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, game, "svg"); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg") {
+		t.Errorf("SVG output doesn't start with <svg: %.40q", svg)
+	}
+	// 5 stars made of 7 pieces, 10 ships, and 3 connections
+	if n := strings.Count(svg, "<polygon"); n != 7+10+3 {
+		t.Errorf("SVG has %d polygons, want %d", n, 7+10+3)
+	}
+	if !strings.Contains(svg, ">North&#39;s homeworld</text>") {
+		t.Errorf("SVG doesn't name North's homeworld")
+	}
+
+	buf.Reset()
+	if err := Render(&buf, game, "png"); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := layout(game)
+	if b := img.Bounds(); b.Dx() != sc.width || b.Dy() != sc.height {
+		t.Errorf("PNG is %v, want %dx%d", b, sc.width, sc.height)
+	}
+	// Check the middle of the first piece of North's homeworld,
+	// which comes after the connections.
+	s := sc.shapes[3]
+	x := int((s.points[0].x + s.points[1].x + s.points[2].x) / 3)
+	y := int((s.points[0].y + s.points[1].y + s.points[2].y) / 3)
+	if c := img.At(x, y); c != s.fill {
+		t.Errorf("pixel at %d,%d is %v, want %v", x, y, c, s.fill)
+	}
+
+	// The names of the stars are drawn too.
+	bare := layout(game)
+	bare.labels = nil
+	if bytes.Equal(sc.rasterize().Pix, bare.rasterize().Pix) {
+		t.Errorf("PNG doesn't name the stars")
+	}
+
+	if err := Render(&buf, game, "bmp"); err == nil {
+		t.Errorf("Render accepted an unknown format")
+	}
+}
@@ -0,0 +1,116 @@
+package homeworlds
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A RepetitionPolicy decides what happens when a position recurs.
+type RepetitionPolicy int
+
+const (
+	// AllowRepetition places no limit on repeated positions.
+	AllowRepetition RepetitionPolicy = iota
+
+	// DrawByRepetition ends the game in a draw
+	// when the same position occurs for the third time.
+	DrawByRepetition
+
+	// ForbidRepetition forbids any turn which
+	// returns to a position which has already occurred.
+	ForbidRepetition
+)
+
+var repetitionNames = map[RepetitionPolicy]string{
+	AllowRepetition:  "allow",
+	DrawByRepetition: "draw",
+	ForbidRepetition: "forbid",
+}
+
+func (p RepetitionPolicy) String() string {
+	if s, ok := repetitionNames[p]; ok {
+		return s
+	}
+	return "Unknown repetition policy [BUG]"
+}
+
+// Set parses a policy name: "allow", "draw" or "forbid".
+// It allows a RepetitionPolicy to be used as a flag.Value.
+func (p *RepetitionPolicy) Set(s string) error {
+	for q, name := range repetitionNames {
+		if s == name {
+			*p = q
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown repetition policy %q", s)
+}
+
+// key returns a string which identifies the position,
+// for detecting repetitions.
+// The order of the stars other than the homeworlds doesn't matter.
+// It is the same as the binary encoding of the position.
+func (pos Position) key() string {
+	var buf [4 + 9*16]byte
+	return string(pos.appendKey(buf[:0]))
+}
+
+// appendKey appends the key of the position to b.
+func (pos Position) appendKey(b []byte) []byte {
+	var buf [16]Dwarf
+	stars := append(buf[:0], pos.stars...)
+	// insertion sort; there are never very many stars
+	for i := 3; i < len(stars); i++ {
+		for j := i; j > 2 && stars[j].less(&stars[j-1]); j-- {
+			stars[j], stars[j-1] = stars[j-1], stars[j]
+		}
+	}
+	b = append(b, pos.player)
+	b = pos.bank.append(b)
+	for i := range stars {
+		b = stars[i].pieces.append(b)
+		b = stars[i].ships[North].append(b)
+		b = stars[i].ships[South].append(b)
+	}
+	return b
+}
+
+func (s *Dwarf) less(r *Dwarf) bool {
+	if s.pieces != r.pieces {
+		return s.pieces.bits < r.pieces.bits
+	}
+	if s.ships[North] != r.ships[North] {
+		return s.ships[North].bits < r.ships[North].bits
+	}
+	return s.ships[South].bits < r.ships[South].bits
+}
+
+// append appends the 24 significant bits of the bank to b.
+func (b Bank) append(buf []byte) []byte {
+	return append(buf, byte(b.bits), byte(b.bits>>8), byte(b.bits>>16))
+}
+
+// Repetitions returns the number of times the current position
+// has occurred at the end of a turn.
+func (g *Game) Repetitions() int {
+	return g.positions()[PositionFromGame(g).key()]
+}
+
+// CheckRepetition returns an error if ending the turn now
+// would return to a position forbidden by the repetition policy.
+func (g *Game) CheckRepetition() error {
+	if g.Repetition != ForbidRepetition {
+		return nil
+	}
+	pos := PositionFromGame(g)
+	pos.endturn()
+	if g.positions()[pos.key()] > 0 {
+		return errors.New("EndTurn: position has already occurred")
+	}
+	return nil
+}
+
+// IsDraw reports whether the game has been drawn by repetition.
+func (g *Game) IsDraw() bool {
+	return g.Repetition == DrawByRepetition && g.Repetitions() >= 3
+}
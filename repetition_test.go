@@ -0,0 +1,42 @@
+package homeworlds
+
+import "testing"
+
+func TestDrawByRepetition(t *testing.T) {
+	// the starting position counts as its first occurrence
+	g := game.Copy()
+	g.Repetition = DrawByRepetition
+	for i := 0; i < 3; i++ {
+		g.EndTurn()
+		if g.IsOver() {
+			t.Fatalf("game over after %d passes", i+1)
+		}
+	}
+	g.EndTurn()
+	if !g.IsDraw() || !g.IsOver() {
+		t.Errorf("expected a draw after four passes, got %d repetitions", g.Repetitions())
+	}
+}
+
+func TestForbidRepetition(t *testing.T) {
+	g := game.Copy()
+	g.Repetition = ForbidRepetition
+	g.EndTurn()
+	g.EndTurn()
+	if err := g.CheckRepetition(); err == nil {
+		t.Errorf("expected passing a third time to be forbidden")
+	}
+}
+
+func TestPositionKey(t *testing.T) {
+	pos := PositionFromGame(game)
+	tmp := pos.copy()
+	tmp.stars[2], tmp.stars[4] = tmp.stars[4], tmp.stars[2]
+	if pos.key() != tmp.key() {
+		t.Errorf("key depends on the order of stars")
+	}
+	tmp.endturn()
+	if pos.key() == tmp.key() {
+		t.Errorf("key ignores the current player")
+	}
+}
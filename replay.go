@@ -0,0 +1,225 @@
+package homeworlds
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// RenderFrame draws the game as it was at the given ply of its history,
+// like Render, with the turn which led to it as a caption.
+// Drawing every ply from 0 to g.Ply() gives a replay of the game
+// as a series of images; see RenderFrames.
+func RenderFrame(w io.Writer, g *Game, ply int, format string) error {
+	if ply < 0 || ply > g.Ply() {
+		return fmt.Errorf("RenderFrame: no such ply %d", ply)
+	}
+	return writeFrame(w, frame(g.History(), g.Turns(), ply), format)
+}
+
+// RenderFrames draws every ply of the game's history up to the current ply,
+// like RenderFrame.
+// Each frame is written to the writer which create returns for its ply,
+// which is closed afterwards.
+func RenderFrames(g *Game, format string, create func(ply int) (io.WriteCloser, error)) error {
+	states, turns := g.History(), g.Turns()
+	for ply := 0; ply <= g.Ply(); ply++ {
+		w, err := create(ply)
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(w, frame(states, turns, ply), format); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFrame(w io.Writer, sc *scene, format string) error {
+	switch format {
+	case "svg":
+		return sc.writeSVG(w)
+	case "png":
+		return png.Encode(w, sc.rasterize())
+	}
+	return fmt.Errorf("RenderFrame: unknown format %q", format)
+}
+
+// RenderReplay draws the game's history up to the current ply
+// as an animated GIF, with one frame per ply.
+// Delay is the time each frame is shown, in hundredths of a second.
+func RenderReplay(w io.Writer, g *Game, delay int) error {
+	var frames []*scene
+	width := 0
+	states, turns := g.History(), g.Turns()
+	for ply := 0; ply <= g.Ply(); ply++ {
+		sc := frame(states, turns, ply)
+		frames = append(frames, sc)
+		if sc.width > width {
+			width = sc.width
+		}
+	}
+	// Every frame is drawn at the same width, centered,
+	// so that the caption can use all of it.
+	height := 0
+	for _, sc := range frames {
+		sc.shift(float64(width-sc.width) / 2)
+		sc.width = width
+		if h := sc.totalHeight(); h > height {
+			height = h
+		}
+	}
+	anim := &gif.GIF{Config: image.Config{ColorModel: replayPalette, Width: width, Height: height}}
+	for i, sc := range frames {
+		p := image.NewPaletted(image.Rect(0, 0, width, height), replayPalette)
+		draw.Draw(p, p.Bounds(), image.NewUniform(backgroundColor), image.Point{}, draw.Src)
+		img := sc.rasterize()
+		draw.Draw(p, img.Bounds(), img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, p)
+		d := delay
+		if i == len(frames)-1 {
+			// linger on the final position
+			d *= 3
+		}
+		anim.Delay = append(anim.Delay, d)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+var replayPalette = color.Palette{
+	backgroundColor,
+	outlineColor,
+	connectionColor,
+	pieceColors[Red],
+	pieceColors[Yellow],
+	pieceColors[Green],
+	pieceColors[Blue],
+}
+
+// frame lays out the state at the given ply of a game's history with a caption.
+// States and turns are the game's History and Turns.
+func frame(states []*Game, turns []Turn, ply int) *scene {
+	sc := layout(states[ply])
+	if sc.width < minFrameWidth {
+		// leave room for the caption
+		sc.shift(float64(minFrameWidth-sc.width) / 2)
+		sc.width = minFrameWidth
+	}
+	if ply == 0 {
+		sc.caption = "Start"
+	} else {
+		prev := states[ply-1]
+		sc.caption = fmt.Sprintf("%d. %s", ply, prev.CurrentPlayer)
+		if t := turns[ply-1]; t != nil {
+			sc.caption += ": " + prev.FormatTurn(t)
+		}
+	}
+	return sc
+}
+
+// font holds a 5x7 bitmap for each character drawText can draw.
+// Each byte is a row, with the leftmost pixel in bit 4.
+var font = map[rune]string{
+	'A': "0e1111111f1111", 'B': "1e11111e11111e", 'C': "0e11101010110e",
+	'D': "1c12111111121c", 'E': "1f10101e10101f", 'F': "1f10101e101010",
+	'G': "0e11101711110f", 'H': "1111111f111111", 'I': "0e04040404040e",
+	'J': "0702020202120c", 'K': "11121418141211", 'L': "1010101010101f",
+	'M': "111b1515111111", 'N': "11111915131111", 'O': "0e11111111110e",
+	'P': "1e11111e101010", 'Q': "0e11111115120d", 'R': "1e11111e141211",
+	'S': "0f10100e01011e", 'T': "1f040404040404", 'U': "1111111111110e",
+	'V': "11111111110a04", 'W': "1111111515150a", 'X': "11110a040a1111",
+	'Y': "1111110a040404", 'Z': "1f01020408101f",
+	'0': "0e11131519110e", '1': "040c040404040e", '2': "0e11010204081f",
+	'3': "1f02040201110e", '4': "02060a121f0202", '5': "1f101e0101110e",
+	'6': "0608101e11110e", '7': "1f010204080808", '8': "0e11110e11110e",
+	'9':  "0e11110f01020c",
+	'\'': "0c040800000000", ';': "000c0c000c0408", ',': "000000000c0408",
+	'.': "00000000000c0c", '-': "0000001f000000", ':': "000c0c000c0c00",
+	'(': "02040808080402", ')': "08040202020408", '/': "00010204081000",
+	'*': "0004150e150400", '?': "0e110102040004", ' ': "00000000000000",
+}
+
+// shift moves everything in the scene to the right by dx.
+func (sc *scene) shift(dx float64) {
+	for i := range sc.shapes {
+		for j := range sc.shapes[i].points {
+			sc.shapes[i].points[j].x += dx
+		}
+	}
+	for i := range sc.labels {
+		sc.labels[i].at.x += dx
+	}
+}
+
+const (
+	minFrameWidth = 3 * cellWidth
+	textScale     = 2
+	lineHeight    = 9 * textScale
+)
+
+// captionLines splits the caption into lines which fit the width of the scene.
+func (sc *scene) captionLines() []string {
+	if sc.caption == "" {
+		return nil
+	}
+	max := (sc.width - 16) / (6 * textScale)
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(sc.caption) {
+		if line != "" && len(line)+1+len(word) > max {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// totalHeight returns the height of the scene including its caption.
+func (sc *scene) totalHeight() int {
+	if n := len(sc.captionLines()); n > 0 {
+		return sc.height + n*lineHeight + 12
+	}
+	return sc.height
+}
+
+// drawText draws text in capital letters with its top left corner at x, y,
+// with each pixel of the font scaled up to a square of the given size.
+func drawText(img *image.RGBA, text string, x, y, scale int, c color.RGBA) {
+	for _, r := range strings.ToUpper(text) {
+		glyph, ok := font[r]
+		if !ok {
+			glyph = font['?']
+		}
+		rows, _ := hex.DecodeString(glyph)
+		for i, row := range rows {
+			for j := 0; j < 5; j++ {
+				if row&(0x10>>uint(j)) == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						px, py := x+j*scale+dx, y+i*scale+dy
+						if image.Pt(px, py).In(img.Bounds()) {
+							img.SetRGBA(px, py, c)
+						}
+					}
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
@@ -0,0 +1,61 @@
+package homeworlds
+
+import (
+	"bytes"
+	"image/gif"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderReplay(t *testing.T) {
+	rec, err := ReadSDG(strings.NewReader(sdgLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderReplay(&buf, g, 100); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 10 {
+		t.Errorf("got %d frames, want 10", len(anim.Image))
+	}
+
+	buf.Reset()
+	if err := RenderFrame(&buf, g, 3, "svg"); err != nil {
+		t.Fatal(err)
+	}
+	if want := ">3. North: build G1 at North&#39;s homeworld</text>"; !strings.Contains(buf.String(), want) {
+		t.Errorf("frame 3 doesn't have the caption %s", want)
+	}
+	if err := RenderFrame(&buf, g, 10, "svg"); err == nil {
+		t.Errorf("RenderFrame accepted ply 10")
+	}
+
+	var frames []*bytes.Buffer
+	err = RenderFrames(g, "svg", func(ply int) (io.WriteCloser, error) {
+		frames = append(frames, new(bytes.Buffer))
+		return nopCloser{frames[ply]}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	RenderFrame(&buf, g, 3, "svg")
+	if len(frames) != 10 || frames[3].String() != buf.String() {
+		t.Errorf("RenderFrames wrote %d frames, or frame 3 differs from RenderFrame", len(frames))
+	}
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
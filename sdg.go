@@ -0,0 +1,116 @@
+package homeworlds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// ReadSDG reads a game log in the notation used by SuperDuperGames.
+//
+// Each line holds one turn.
+// A turn is a list of commands separated by semicolons,
+// optionally preceded by a turn number such as "12." or "12)".
+// The commands are:
+//
+//	homeworld r2 b1 g3
+//	discover g1 alpha y3 beta
+//	move g1 alpha beta
+//	build g1 alpha
+//	trade g1 b1 alpha
+//	attack r1 alpha
+//	sacrifice y3 alpha
+//	catastrophe alpha red
+//	pass
+//
+// Homeworlds are named after the players, North and South,
+// unless a name follows the homeworld command.
+// Blank lines and lines beginning with # are ignored.
+func ReadSDG(r io.Reader) (*Record, error) {
+	rec := new(Record)
+	s := bufio.NewScanner(r)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = trimTurnNumber(line)
+		t, err := ParseTurn(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		rec.Turns = append(rec.Turns, t)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	rec.nameHomeworlds()
+	return rec, nil
+}
+
+// trimTurnNumber removes a leading turn number from a line.
+func trimTurnNumber(line string) string {
+	i := 0
+	for i < len(line) && '0' <= line[i] && line[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(line) {
+		return line
+	}
+	switch line[i] {
+	case '.', ')', ':':
+		i++
+	default:
+		if !unicode.IsSpace(rune(line[i])) {
+			return line
+		}
+	}
+	return strings.TrimSpace(line[i:])
+}
+
+// WriteSDG writes a game record in the notation used by SuperDuperGames,
+// one turn per line.
+func WriteSDG(w io.Writer, rec *Record) error {
+	for i, t := range rec.Turns {
+		pl := Player(i % 2)
+		s := make([]string, len(t))
+		for j, c := range t {
+			s[j] = fmtSDG(c, pl)
+		}
+		if len(t) == 0 {
+			s = []string{"pass"}
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(s, "; ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fmtSDG formats a command taken by the given player in SDG notation.
+func fmtSDG(c Command, pl Player) string {
+	lower := strings.ToLower
+	switch c.Type {
+	case Homeworld:
+		s := lower(fmt.Sprintf("homeworld %s %s %s", c.Star[0], c.Star[1], c.Ship))
+		if c.System != pl.String() {
+			s += " " + c.System
+		}
+		return s
+	case Discover:
+		return fmt.Sprintf("discover %s %s %s %s", lower(c.Ship.String()), c.System, lower(c.NewShip.String()), c.NewSystem)
+	case Move:
+		return fmt.Sprintf("move %s %s %s", lower(c.Ship.String()), c.System, c.NewSystem)
+	case Trade:
+		return fmt.Sprintf("trade %s %s %s", lower(c.Ship.String()), lower(c.NewShip.String()), c.System)
+	case Build, Attack, Sacrifice:
+		return fmt.Sprintf("%s %s %s", lower(c.Type.String()), lower(c.Ship.String()), c.System)
+	case Catastrope:
+		return fmt.Sprintf("catastrophe %s %s", c.System, lower(c.Color.String()))
+	}
+	return c.String()
+}
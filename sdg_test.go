@@ -0,0 +1,83 @@
+package homeworlds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var sdgLog = `1. homeworld r2 b1 g3
+2. homeworld y3 b2 g3
+3. build g1 North
+4. build g1 South
+5. trade g1 y1 home
+6. discover g1 South r1 alpha
+7. build g1 North
+8. build g1 alpha
+9. sacrifice g3 North; build g2 North; build g2 North; build g2 North; catastrophe North green
+`
+
+func TestReadSDG(t *testing.T) {
+	rec, err := ReadSDG(strings.NewReader(sdgLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Turns) != 9 {
+		t.Fatalf("got %d turns, want 9", len(rec.Turns))
+	}
+	g, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	north := g.Stars["North"]
+	if len(north.Ships[North]) != 1 || north.Ships[North][0] != Y1 {
+		t.Errorf("North's ships at home = %v, want [Y1]", north.Ships[North])
+	}
+	if g.Bank[G2] != 3 || g.Bank[G3] != 2 {
+		t.Errorf("bank has %d G2 and %d G3, want 3 and 2", g.Bank[G2], g.Bank[G3])
+	}
+	if g.CurrentPlayer != South {
+		t.Errorf("current player = %s, want South", g.CurrentPlayer)
+	}
+
+	rec2, err := g.Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	WriteSDG(&buf, rec2)
+	want := trimTurnNumbers(sdgLog)
+	if got := buf.String(); got != want {
+		t.Errorf("WriteSDG:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlayErrors(t *testing.T) {
+	tests := []string{
+		"homeworld r2 b1 g3\nhomeworld y3 b2 g3\nbuild g1 North; build g1 North",
+		"homeworld r2 b1 g3\nhomeworld y3 b2 g3\nmove g3 North South",
+		"homeworld r2 b1 g3\nhomeworld y3 b2 g3\nbuild g1 South",
+		"homeworld r2 b1 g3\nhomeworld y3 b2 g3\nbuild g1 North; sacrifice g3 North",
+	}
+	for _, s := range tests {
+		rec, err := ReadSDG(strings.NewReader(s))
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if _, err := rec.Replay(); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func trimTurnNumbers(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		lines[i] = trimTurnNumber(line)
+		if line != "" && !strings.HasSuffix(lines[i], "\n") {
+			lines[i] += "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
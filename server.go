@@ -0,0 +1,278 @@
+package homeworlds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// A Message is sent between a Server and its clients.
+// Messages are encoded as JSON, one per line.
+//
+// When a client connects, the server replies with a "wait" message
+// until a second client connects to play against it.
+// Then each client is sent a "start" message with the player
+// they are playing as and the new game:
+//
+//	{"type": "wait"}
+//	{"type": "start", "player": "North", "game": {...}}
+//
+// A client plays a turn by sending a "turn" message
+// or gives up by sending a "resign" message:
+//
+//	{"type": "turn", "turn": ["sacrifice Y2 north", "move G1 north alpha"]}
+//	{"type": "resign"}
+//
+// Each turn played is sent to both clients, along with the resulting game.
+// If a turn is out of order or can't be played, the server sends
+// an "error" message to the client who sent it and waits for another.
+//
+//	{"type": "turn", "player": "North", "turn": [...], "game": {...}}
+//	{"type": "error", "error": "Build: no such system \"alpha\""}
+//
+// When the game is over, both clients are sent an "end" message
+// and the connections are closed.
+// The winner is omitted if the game is drawn.
+// A client who disconnects or runs out of time loses.
+//
+//	{"type": "end", "winner": "South", "result": "South wins"}
+//	{"type": "end", "winner": "North", "result": "South ran out of time"}
+type Message struct {
+	Type   string  `json:"type"`
+	Player *Player `json:"player,omitempty"`
+	Turn   Turn    `json:"turn,omitempty"`
+	Game   *Game   `json:"game,omitempty"`
+	Winner *Player `json:"winner,omitempty"`
+	Result string  `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// A Server hosts games between pairs of clients
+// which connect to it.
+// The server checks that each turn is legal
+// and is played by the right player.
+type Server struct {
+	// Repetition is the repetition policy for the server's games.
+	Repetition RepetitionPolicy
+
+	// TurnTimeout limits how long a player may take over each turn.
+	// A player who takes longer loses the game.
+	// If zero, there is no limit.
+	TurnTimeout time.Duration
+
+	// WriteTimeout limits how long sending a message to a client may take.
+	// If zero, there is no limit.
+	WriteTimeout time.Duration
+
+	// ErrorLog logs failed connections and the results of games.
+	// If nil, nothing is logged.
+	ErrorLog *log.Logger
+}
+
+// Serve accepts connections on l and plays a game
+// between each pair of clients, in the order they connect.
+// The first client to connect plays North.
+// A client who disconnects while waiting for an opponent is dropped.
+// Serve always returns a non-nil error.
+func (s *Server) Serve(l net.Listener) error {
+	conns := make(chan net.Conn)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				errc <- err
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	var waiting *peer
+	for {
+		var waitc <-chan clientMessage
+		if waiting != nil {
+			waitc = waiting.in
+		}
+		select {
+		case err := <-errc:
+			if waiting != nil {
+				waiting.close()
+			}
+			return err
+		case m := <-waitc:
+			if m.err != nil {
+				s.logf("%s: %v", waiting.conn.RemoteAddr(), m.err)
+				waiting.close()
+				waiting = nil
+				continue
+			}
+			s.send(waiting, Message{Type: "error", Error: "the game has not started"})
+		case conn := <-conns:
+			p := newPeer(conn)
+			if waiting == nil {
+				if err := s.send(p, Message{Type: "wait"}); err != nil {
+					p.close()
+					continue
+				}
+				waiting = p
+				continue
+			}
+			go s.play([2]*peer{waiting, p})
+			waiting = nil
+		}
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	}
+}
+
+// A peer is a client connected to the server.
+// Messages from the client are read as they arrive and sent on in.
+type peer struct {
+	conn net.Conn
+	enc  *json.Encoder
+	in   chan clientMessage
+	done chan struct{}
+}
+
+func newPeer(conn net.Conn) *peer {
+	p := &peer{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		in:   make(chan clientMessage),
+		done: make(chan struct{}),
+	}
+	go receive(conn, p.in, p.done)
+	return p
+}
+
+// close closes the connection and stops reading from it.
+func (p *peer) close() {
+	close(p.done)
+	p.conn.Close()
+}
+
+// send sends a message to a client, and logs any error.
+func (s *Server) send(p *peer, m Message) error {
+	if s.WriteTimeout > 0 {
+		p.conn.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
+	err := p.enc.Encode(m)
+	if err != nil {
+		s.logf("%s: %v", p.conn.RemoteAddr(), err)
+	}
+	return err
+}
+
+// A clientMessage is a message received from a client.
+// err is set if the connection failed.
+type clientMessage struct {
+	msg Message
+	err error
+}
+
+// play plays a game between two connected clients.
+func (s *Server) play(peers [2]*peer) {
+	defer peers[0].close()
+	defer peers[1].close()
+	send := func(pl Player, m Message) {
+		s.send(peers[pl], m)
+	}
+
+	g := NewGame(2)
+	g.Repetition = s.Repetition
+	for _, pl := range []Player{North, South} {
+		pl := pl
+		send(pl, Message{Type: "start", Player: &pl, Game: g})
+	}
+	end := runGame(g, [2]<-chan clientMessage{peers[0].in, peers[1].in}, send, s.TurnTimeout)
+	send(North, end)
+	send(South, end)
+	s.logf("%s vs %s: %s", peers[North].conn.RemoteAddr(), peers[South].conn.RemoteAddr(), end.Result)
+}
+
+// runGame plays the turns received from the players until the game is over,
+// and returns the message announcing the result.
+// If timeout is not zero, a player who takes longer than that over a turn loses.
+func runGame(g *Game, in [2]<-chan clientMessage, send func(Player, Message), timeout time.Duration) Message {
+	var deadline time.Time
+	for !g.IsOver() {
+		var timer <-chan time.Time
+		if timeout > 0 {
+			if deadline.IsZero() {
+				deadline = time.Now().Add(timeout)
+			}
+			timer = time.After(deadline.Sub(time.Now()))
+		}
+		var m clientMessage
+		var from Player
+		select {
+		case m = <-in[North]:
+			from = North
+		case m = <-in[South]:
+			from = South
+		case <-timer:
+			other := Player(1 - g.CurrentPlayer)
+			return Message{Type: "end", Winner: &other, Result: fmt.Sprintf("%s ran out of time", g.CurrentPlayer)}
+		}
+		other := Player(1 - from)
+		switch {
+		case m.err != nil:
+			return Message{Type: "end", Winner: &other, Result: fmt.Sprintf("%s disconnected", from)}
+		case m.msg.Type == "resign":
+			return Message{Type: "end", Winner: &other, Result: fmt.Sprintf("%s resigned", from)}
+		case m.msg.Type == "invalid":
+			send(from, Message{Type: "error", Error: m.msg.Error})
+		case m.msg.Type != "turn":
+			send(from, Message{Type: "error", Error: fmt.Sprintf("unknown message type %q", m.msg.Type)})
+		case from != g.CurrentPlayer:
+			send(from, Message{Type: "error", Error: "it is not your turn"})
+		default:
+			if err := g.Play(m.msg.Turn); err != nil {
+				send(from, Message{Type: "error", Error: err.Error()})
+				continue
+			}
+			deadline = time.Time{}
+			for _, pl := range []Player{North, South} {
+				send(pl, Message{Type: "turn", Player: &from, Turn: m.msg.Turn, Game: g})
+			}
+		}
+	}
+	if g.IsDraw() {
+		return Message{Type: "end", Result: "draw by repetition"}
+	}
+	w := g.Winner()
+	return Message{Type: "end", Winner: &w, Result: fmt.Sprintf("%s wins", w)}
+}
+
+// receive reads messages from a client and sends them on in
+// until the connection fails or done is closed.
+// Messages which aren't understood are passed on with the type "invalid".
+func receive(r io.Reader, in chan<- clientMessage, done <-chan struct{}) {
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		var m Message
+		if err == nil {
+			if uerr := json.Unmarshal(raw, &m); uerr != nil {
+				m = Message{Type: "invalid", Error: uerr.Error()}
+			}
+		}
+		select {
+		case in <- clientMessage{m, err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
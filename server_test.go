@@ -0,0 +1,137 @@
+package homeworlds
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go new(Server).Serve(l)
+
+	type client struct {
+		enc *json.Encoder
+		dec *json.Decoder
+	}
+	var clients [2]client
+	recv := func(pl Player, typ string) Message {
+		var m Message
+		if err := clients[pl].dec.Decode(&m); err != nil {
+			t.Fatalf("%s: %v", pl, err)
+		}
+		if m.Type != typ {
+			t.Fatalf("%s: got %+v, want a %q message", pl, m, typ)
+		}
+		return m
+	}
+	play := func(pl Player, s string) {
+		turn, err := ParseTurn(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := clients[pl].enc.Encode(Message{Type: "turn", Turn: turn}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for pl := range clients {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		clients[pl] = client{json.NewEncoder(conn), json.NewDecoder(conn)}
+	}
+	recv(North, "wait")
+	for _, pl := range []Player{North, South} {
+		m := recv(pl, "start")
+		if m.Player == nil || *m.Player != pl {
+			t.Errorf("%s: start message has player %v", pl, m.Player)
+		}
+	}
+
+	play(South, "homeworld R1 B2 G3 south")
+	recv(South, "error")
+	play(North, "homeworld R1 B2 G3 north")
+	for _, pl := range []Player{North, South} {
+		m := recv(pl, "turn")
+		if m.Game == nil || m.Game.Homeworld(North) == nil {
+			t.Errorf("%s: turn message has game %v", pl, m.Game)
+		}
+	}
+	play(South, "build G1 south")
+	recv(South, "error")
+	if err := clients[South].enc.Encode(Message{Type: "resign"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, pl := range []Player{North, South} {
+		m := recv(pl, "end")
+		if m.Winner == nil || *m.Winner != North || m.Result != "South resigned" {
+			t.Errorf("%s: got %+v, want North to win by resignation", pl, m)
+		}
+	}
+}
+
+func TestServerDisconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go (&Server{TurnTimeout: 200 * time.Millisecond}).Serve(l)
+
+	dial := func() (net.Conn, *json.Decoder) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, json.NewDecoder(conn)
+	}
+	recv := func(dec *json.Decoder, typ string) Message {
+		var m Message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("waiting for %q: %v", typ, err)
+		}
+		if m.Type != typ {
+			t.Fatalf("got %+v, want a %q message", m, typ)
+		}
+		return m
+	}
+
+	// A client who leaves before the game starts isn't paired.
+	conn, dec := dial()
+	recv(dec, "wait")
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	north, ndec := dial()
+	defer north.Close()
+	recv(ndec, "wait")
+	south, sdec := dial()
+	defer south.Close()
+	recv(ndec, "start")
+	recv(sdec, "start")
+
+	// A client who disconnects during the game loses.
+	north.Close()
+	if m := recv(sdec, "end"); m.Winner == nil || *m.Winner != South || m.Result != "North disconnected" {
+		t.Errorf("got %+v, want South to win when North disconnects", m)
+	}
+
+	// So does a client who takes too long.
+	north, ndec = dial()
+	defer north.Close()
+	recv(ndec, "wait")
+	south, sdec = dial()
+	defer south.Close()
+	recv(ndec, "start")
+	recv(sdec, "start")
+	if m := recv(sdec, "end"); m.Winner == nil || *m.Winner != South || m.Result != "North ran out of time" {
+		t.Errorf("got %+v, want South to win when North runs out of time", m)
+	}
+}
@@ -0,0 +1,100 @@
+package homeworlds
+
+import "errors"
+
+// ChooseHomeworld chooses a homeworld and a starting ship
+// for the current player, who must not have a homeworld yet,
+// and returns them as a turn.
+//
+// The AI doesn't search the setup phase.
+// It prefers a star with pieces of two different sizes,
+// a large ship, and access to green, yellow and blue between them,
+// and avoids the sizes of the opponent's homeworld.
+func (ai *AI) ChooseHomeworld(g *Game) (Turn, error) {
+	pl := g.CurrentPlayer
+	if g.Homeworld(pl) != nil {
+		return nil, errors.New("ChooseHomeworld: player already has a homeworld")
+	}
+	var other *Star
+	for opp, name := range g.Homeworlds {
+		if opp != pl {
+			other = g.Stars[name]
+		}
+	}
+	var (
+		best  Command
+		score = -1000
+		ties  = 0
+	)
+	for a := R1; a <= B3; a++ {
+		for b := a; b <= B3; b++ {
+			for ship := R1; ship <= B3; ship++ {
+				need := make(map[Piece]int)
+				need[a]++
+				need[b]++
+				need[ship]++
+				ok := true
+				for p, n := range need {
+					if g.Bank[p] < n {
+						ok = false
+					}
+				}
+				if !ok {
+					continue
+				}
+				v := homeworldScore(a, b, ship, other)
+				if v > score {
+					score, ties = v, 0
+				}
+				if v == score {
+					// choose randomly between equally good homeworlds
+					ties++
+					if ai.r.Intn(ties) == 0 {
+						best = Command{Type: Homeworld, Star: [2]Piece{a, b}, Ship: ship}
+					}
+				}
+			}
+		}
+	}
+	if score == -1000 {
+		return nil, errors.New("ChooseHomeworld: not enough pieces in the bank")
+	}
+	best.System = pl.String()
+	if _, taken := g.Stars[best.System]; taken {
+		best.System = g.newStarName()()
+	}
+	return Turn{best}, nil
+}
+
+func homeworldScore(a, b, ship Piece, other *Star) int {
+	v := 0
+	has := func(c Color) bool {
+		return a.Color() == c || b.Color() == c || ship.Color() == c
+	}
+	for _, c := range []Color{Green, Yellow, Blue} {
+		if has(c) {
+			v += 2
+		}
+	}
+	if ship.Size() == Large {
+		v += 2
+	}
+	if ship.Color() == Green {
+		v++
+	}
+	if a.Size() == b.Size() {
+		v -= 3
+	}
+	if a.Color() == b.Color() {
+		v--
+	}
+	if other != nil && len(other.Pieces) == 2 {
+		p, q := other.Pieces[0].Size(), other.Pieces[1].Size()
+		if a.Size() == p && b.Size() == q || a.Size() == q && b.Size() == p {
+			// A homeworld with the same sizes as the opponent's
+			// leaves few systems connected to both.
+			v -= 2
+		}
+	}
+	return v
+}
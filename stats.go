@@ -0,0 +1,147 @@
+package homeworlds
+
+import (
+	"fmt"
+	"math"
+)
+
+// A MatchResult counts the wins, draws and losses of one player,
+// or one version of an engine, against another.
+type MatchResult struct {
+	Wins, Draws, Losses int
+}
+
+// Games returns the number of games played.
+func (r MatchResult) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Points returns the player's points: one for each win and half for each draw.
+func (r MatchResult) Points() float64 {
+	return float64(r.Wins) + float64(r.Draws)/2
+}
+
+// Score returns the fraction of the available points which the player won.
+func (r MatchResult) Score() float64 {
+	return r.Points() / float64(r.Games())
+}
+
+// variance returns the variance of the score of a single game.
+func (r MatchResult) variance() float64 {
+	return variance(float64(r.Wins), float64(r.Draws), float64(r.Losses))
+}
+
+// variance returns the variance of the score of a single game,
+// given how many games were won, drawn and lost.
+func variance(w, d, l float64) float64 {
+	n := w + d + l
+	s := (w + d/2) / n
+	return (w*(1-s)*(1-s) + d*(0.5-s)*(0.5-s) + l*s*s) / n
+}
+
+// Elo returns the difference in Elo rating between the player and their opponent
+// implied by the result, along with the margin of error of a 95% confidence interval.
+// The difference is infinite if the player won or lost every game.
+func (r MatchResult) Elo() (diff, margin float64) {
+	if r.Games() == 0 {
+		return 0, math.Inf(1)
+	}
+	s := r.Score()
+	dev := 1.959964 * math.Sqrt(r.variance()/float64(r.Games()))
+	return eloDiff(s), (eloDiff(s+dev) - eloDiff(s-dev)) / 2
+}
+
+// eloDiff converts an expected score into a difference in Elo rating.
+func eloDiff(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+// expectedScore converts a difference in Elo rating into an expected score.
+func expectedScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+func (r MatchResult) String() string {
+	return fmt.Sprintf("+%d =%d -%d", r.Wins, r.Draws, r.Losses)
+}
+
+// An SPRT is a sequential probability ratio test,
+// which decides between two hypotheses about the difference
+// in strength between two players as games are played,
+// so that a match can stop as soon as the result is clear.
+//
+// The null hypothesis is that the first player is Elo0 points stronger
+// than the second, and the alternative is that they are Elo1 points stronger.
+// Alpha is the chance of accepting the alternative when the null hypothesis is true,
+// and Beta the chance of accepting the null hypothesis when the alternative is true.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// An SPRTDecision is the outcome of an SPRT.
+type SPRTDecision int
+
+const (
+	// SPRTContinue means more games are needed.
+	SPRTContinue SPRTDecision = iota
+	// SPRTAcceptH0 means the null hypothesis was accepted.
+	SPRTAcceptH0
+	// SPRTAcceptH1 means the alternative hypothesis was accepted.
+	SPRTAcceptH1
+)
+
+func (d SPRTDecision) String() string {
+	switch d {
+	case SPRTContinue:
+		return "continue"
+	case SPRTAcceptH0:
+		return "H0 accepted"
+	case SPRTAcceptH1:
+		return "H1 accepted"
+	}
+	return fmt.Sprintf("SPRTDecision(%d)", int(d))
+}
+
+// Bounds returns the log-likelihood ratios at which the test
+// accepts the null hypothesis (lower) or the alternative (upper).
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// LLR returns the log-likelihood ratio of the hypotheses given the result.
+// It uses a normal approximation to the distribution of the score,
+// which is good once a few dozen games have been played.
+// If every game had the same result, the variance is estimated
+// as though half a game of each kind had also been played.
+func (t SPRT) LLR(r MatchResult) float64 {
+	n := float64(r.Games())
+	if n == 0 {
+		return 0
+	}
+	v := r.variance()
+	if v == 0 {
+		v = variance(float64(r.Wins)+0.5, float64(r.Draws)+0.5, float64(r.Losses)+0.5)
+	}
+	s0, s1 := expectedScore(t.Elo0), expectedScore(t.Elo1)
+	return n * (s1 - s0) * (2*r.Score() - s0 - s1) / (2 * v)
+}
+
+// Test decides whether the result is enough to accept either hypothesis.
+func (t SPRT) Test(r MatchResult) SPRTDecision {
+	llr := t.LLR(r)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return SPRTAcceptH1
+	case llr <= lower:
+		return SPRTAcceptH0
+	}
+	return SPRTContinue
+}
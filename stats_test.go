@@ -0,0 +1,51 @@
+package homeworlds
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatchResultElo(t *testing.T) {
+	tests := []struct {
+		r            MatchResult
+		diff, margin float64
+	}{
+		{MatchResult{50, 0, 50}, 0, 69.0},
+		{MatchResult{60, 0, 40}, 70.4, 70.6},
+		{MatchResult{30, 40, 30}, 0, 53.2},
+		{MatchResult{25, 50, 5}, 88.7, 45.7},
+	}
+	for _, tt := range tests {
+		diff, margin := tt.r.Elo()
+		if math.Abs(diff-tt.diff) > 0.1 || math.Abs(margin-tt.margin) > 0.1 {
+			t.Errorf("%v: got Elo %.1f ± %.1f, want %.1f ± %.1f", tt.r, diff, margin, tt.diff, tt.margin)
+		}
+	}
+	if diff, _ := (MatchResult{Wins: 10}).Elo(); !math.IsInf(diff, 1) {
+		t.Errorf("Elo of a clean sweep is %v, want +Inf", diff)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}
+	if lower, upper := sprt.Bounds(); math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("bounds are [%.3f, %.3f], want [-2.944, 2.944]", lower, upper)
+	}
+	tests := []struct {
+		r    MatchResult
+		want SPRTDecision
+	}{
+		{MatchResult{10, 5, 8}, SPRTContinue},
+		{MatchResult{300, 100, 200}, SPRTAcceptH1},
+		{MatchResult{150, 300, 200}, SPRTAcceptH0},
+		{MatchResult{Wins: 100}, SPRTAcceptH1},
+		{MatchResult{Losses: 100}, SPRTAcceptH0},
+		{MatchResult{Wins: 1}, SPRTContinue},
+		{MatchResult{Draws: 1}, SPRTContinue},
+	}
+	for _, tt := range tests {
+		if got := sprt.Test(tt.r); got != tt.want {
+			t.Errorf("%v: got %v (LLR %.3f), want %v", tt.r, got, sprt.LLR(tt.r), tt.want)
+		}
+	}
+}
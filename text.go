@@ -49,6 +49,10 @@ func Print(w io.Writer, g *Game) error {
 	for i := 0; i < g.NumPlayers; i++ {
 		pl := Player(i)
 		s := g.Homeworld(pl)
+		if s == nil {
+			fmt.Fprintf(w, "  %s has no homeworld.\n", pl)
+			continue
+		}
 		fmt.Fprintf(w, "  %s's homeworld, a %s star.\n", pl, fmtStar(s.Pieces))
 	}
 	// BUG: Stars is a map, so this prints the stars in a random order.
@@ -70,7 +74,7 @@ func Print(w io.Writer, g *Game) error {
 			// TODO: print player's own homeworld first
 			h := Player(j)
 			s := g.Homeworld(h)
-			if len(s.Ships[pl]) == 0 {
+			if s == nil || len(s.Ships[pl]) == 0 {
 				continue
 			}
 			if pl == h {
@@ -133,6 +137,157 @@ func (c Color) String() string {
 	}
 }
 
+// PrintGrid formats a game as a compact table,
+// with one row for each system.
+//
+// The output looks something like this:
+//
+//     Bank  1 2 3
+//       R   3 3 3
+//       Y   1 2 1
+//       G   - - -
+//       B   2 2 2
+//
+//     #  System  Star   North  South        Connects
+//     1  North*  Y3 B1  G3 G2
+//     2  South*  B2 Y1         G3 G1        3 4 5
+//     3  grover  B3            Y2 G2 G2 G1  2
+//     4  Orion   Y3     G1                  2
+//     5  Virgo   G3     Y1                  2
+//
+//     North to move.
+//
+// Homeworlds are marked with a *,
+// and the last column lists the systems each system is connected to.
+// If color is true, pieces are colored with ANSI escape codes.
+func PrintGrid(w io.Writer, g *Game, color bool) error {
+	fmt.Fprintln(w, "Bank  1 2 3")
+	for _, c := range []Color{Red, Yellow, Green, Blue} {
+		fmt.Fprintf(w, "  %s  ", fmtColor(c.String()[:1], c, color))
+		for size := Small; size <= Large; size++ {
+			n := g.Bank[piece(size, c)]
+			if n == 0 {
+				fmt.Fprint(w, " -")
+			} else {
+				fmt.Fprint(w, " ", fmtColor(fmt.Sprint(n), c, color))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+
+	var stars []*Star
+	for _, name := range g.StarNames() {
+		if s := g.Stars[name]; s != nil {
+			stars = append(stars, s)
+		}
+	}
+	rows := [][]string{{"#", "System", "Star", North.String(), South.String(), "Connects"}}
+	widths := make([]int, len(rows[0]))
+	for i, s := range stars {
+		name := s.Name
+		for pl, hw := range g.Homeworlds {
+			if hw == name {
+				name = pl.String() + "*"
+			}
+		}
+		var links []string
+		for j, t := range stars {
+			if i != j && s.connects(t) {
+				links = append(links, fmt.Sprint(j+1))
+			}
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(i + 1),
+			name,
+			fmtGridPieces(s.Pieces, false),
+			fmtGridPieces(s.Ships[North], false),
+			fmtGridPieces(s.Ships[South], false),
+			strings.Join(links, " "),
+		})
+	}
+	for _, row := range rows {
+		for j, cell := range row {
+			if len(cell) > widths[j] {
+				widths[j] = len(cell)
+			}
+		}
+	}
+	for i, row := range rows {
+		var line string
+		for j, cell := range row {
+			text := cell
+			if i > 0 && color {
+				s := stars[i-1]
+				switch j {
+				case 2:
+					text = fmtGridPieces(s.Pieces, true)
+				case 3:
+					text = fmtGridPieces(s.Ships[North], true)
+				case 4:
+					text = fmtGridPieces(s.Ships[South], true)
+				}
+			}
+			line += text
+			if j < len(row)-1 {
+				line += strings.Repeat(" ", widths[j]-len(cell)+2)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "%s to move.\n", g.CurrentPlayer)
+	return nil
+}
+
+// BoardPrinter returns a function which prints a game
+// in the given style: "prose", like Print, or "grid", like PrintGrid.
+// Color is passed on to PrintGrid.
+func BoardPrinter(style string, color bool) (func(io.Writer, *Game) error, error) {
+	switch style {
+	case "prose":
+		return Print, nil
+	case "grid":
+		return func(w io.Writer, g *Game) error {
+			return PrintGrid(w, g, color)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown board style %q", style)
+}
+
+// fmtGridPieces formats pieces for PrintGrid, largest first.
+func fmtGridPieces(p []Piece, color bool) string {
+	p = append([]Piece(nil), p...)
+	sort.SliceStable(p, func(i, j int) bool {
+		if p[i].Size() != p[j].Size() {
+			return p[i].Size() > p[j].Size()
+		}
+		return p[i].Color() < p[j].Color()
+	})
+	s := make([]string, len(p))
+	for i, p := range p {
+		s[i] = fmtColor(p.String(), p.Color(), color)
+	}
+	return strings.Join(s, " ")
+}
+
+var ansiColors = [...]string{
+	Red:    "\x1b[31m",
+	Yellow: "\x1b[33m",
+	Green:  "\x1b[32m",
+	Blue:   "\x1b[34m",
+}
+
+// fmtColor wraps s in ANSI escape codes for the color c,
+// if color is true.
+func fmtColor(s string, c Color, color bool) string {
+	if !color {
+		return s
+	}
+	return ansiColors[c] + s + "\x1b[0m"
+}
+
 func (s Size) String() string {
 	switch s {
 	case Small:
@@ -107,3 +107,22 @@ func TestFmtShips(T *testing.T) {
 		}
 	}
 }
+
+func ExamplePrintGrid() {
+	PrintGrid(os.Stdout, game, false)
+	// Output:
+	// Bank  1 2 3
+	//   R   3 3 3
+	//   Y   1 2 1
+	//   G   - - -
+	//   B   2 2 2
+	//
+	// #  System  Star   North  South        Connects
+	// 1  North*  Y3 B1  G3 G2
+	// 2  South*  B2 Y1         G3 G1        3 4 5
+	// 3  grover  B3            Y2 G2 G2 G1  2
+	// 4  Orion   Y3     G1                  2
+	// 5  Virgo   G3     Y1                  2
+	//
+	// North to move.
+}